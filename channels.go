@@ -0,0 +1,139 @@
+package impulse
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ChannelUsage returns the number of non-empty pattern cells in each channel
+// of m, across all patterns.
+func (m *Module) ChannelUsage() [64]int {
+	var usage [64]int
+	for _, p := range m.Patterns {
+		if p == nil {
+			continue
+		}
+		for i := range p.Rows {
+			for ch, c := range p.Rows[i] {
+				if !c.IsEmpty() {
+					usage[ch]++
+				}
+			}
+		}
+	}
+	return usage
+}
+
+// UsedChannels returns the indices of channels of m that contain pattern data,
+// in ascending order.
+func (m *Module) UsedChannels() []int {
+	var used []int
+	for ch, n := range m.ChannelUsage() {
+		if n > 0 {
+			used = append(used, ch)
+		}
+	}
+	return used
+}
+
+// RemapChannels moves the data of each channel i of m to channel mapping[i],
+// including pattern data, ChannelPanning, ChannelVolume, and ChannelNames.
+// Channels with an index of len(mapping) or greater are not moved. A mapping
+// of -1 discards the channel's pattern data. Channels that are not the target
+// of any mapping are left empty, with default panning and volume.
+func (m *Module) RemapChannels(mapping []int) error {
+	if len(mapping) > 64 {
+		return errors.New("channel mapping has more than 64 entries")
+	}
+
+	// build full mapping and check for collisions
+	var full [64]int
+	var source [64]int // new channel -> old channel, or -1
+	for i := range source {
+		source[i] = -1
+	}
+	for i := range full {
+		full[i] = i
+		if i < len(mapping) {
+			full[i] = mapping[i]
+		}
+		switch {
+		case full[i] == -1:
+			continue
+		case full[i] < 0 || full[i] >= 64:
+			return fmt.Errorf("channel %d mapped to invalid channel %d", i,
+				full[i])
+		case source[full[i]] != -1:
+			return fmt.Errorf("channels %d and %d both mapped to channel %d",
+				source[full[i]], i, full[i])
+		}
+		source[full[i]] = i
+	}
+
+	for _, p := range m.Patterns {
+		if p == nil {
+			continue
+		}
+		for i := range p.Rows {
+			old := p.Rows[i]
+			for ch := range p.Rows[i] {
+				if source[ch] >= 0 {
+					p.Rows[i][ch] = old[source[ch]]
+				} else {
+					p.Rows[i][ch] = EmptyCell
+				}
+			}
+		}
+	}
+
+	remap := func(values []uint8, def uint8) {
+		if values == nil {
+			return
+		}
+		old := append([]uint8(nil), values...)
+		for ch := range values {
+			if source[ch] >= 0 && source[ch] < len(old) {
+				values[ch] = old[source[ch]]
+			} else {
+				values[ch] = def
+			}
+		}
+	}
+	remap(m.ChannelPanning, 32)
+	remap(m.ChannelVolume, 64)
+	if m.ChannelNames != nil {
+		old := append([]string(nil), m.ChannelNames...)
+		for ch := range m.ChannelNames {
+			if source[ch] >= 0 && source[ch] < len(old) {
+				m.ChannelNames[ch] = old[source[ch]]
+			} else {
+				m.ChannelNames[ch] = ""
+			}
+		}
+	}
+
+	return nil
+}
+
+// CompactChannels moves the channels of m that contain pattern data to the
+// lowest channel indices, preserving their relative order, and returns the
+// mapping used, as passed to RemapChannels.
+func (m *Module) CompactChannels() []int {
+	mapping := make([]int, 64)
+	usage := m.ChannelUsage()
+	next := 0
+	for ch, n := range usage {
+		if n > 0 {
+			mapping[ch] = next
+			next++
+		}
+	}
+	for ch, n := range usage {
+		if n == 0 {
+			mapping[ch] = next
+			next++
+		}
+	}
+	m.RemapChannels(mapping)
+	return mapping
+}
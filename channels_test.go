@@ -0,0 +1,136 @@
+package impulse
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func newChannelTestModule() *Module {
+	p := NewPattern(32)
+	p.Rows[0][2] = Cell{Note: 60, Instrument: 1, VolPan: VolPanNone}
+	p.Rows[1][2] = Cell{Note: NoteOff, VolPan: VolPanNone}
+	p.Rows[0][5] = Cell{Note: NoteNone, VolPan: 32}
+	m := &Module{
+		ChannelPanning: make([]uint8, 64),
+		ChannelVolume:  make([]uint8, 64),
+		ChannelNames:   make([]string, 64),
+		Patterns:       []*Pattern{p, nil},
+	}
+	for i := range m.ChannelPanning {
+		m.ChannelPanning[i] = uint8(i)
+		m.ChannelVolume[i] = uint8(64 - i)
+	}
+	m.ChannelNames[2] = "lead"
+	m.ChannelNames[5] = "bass"
+	return m
+}
+
+func TestChannelUsage(t *testing.T) {
+	m := newChannelTestModule()
+	var want [64]int
+	want[2], want[5] = 2, 1
+	if got := m.ChannelUsage(); got != want {
+		t.Errorf("Module.ChannelUsage() == %v; want %v", got, want)
+	}
+	if got, want := m.UsedChannels(), []int{2, 5}; !reflect.DeepEqual(got,
+		want) {
+		t.Errorf("Module.UsedChannels() == %v; want %v", got, want)
+	}
+}
+
+func TestRemapChannels(t *testing.T) {
+	// test invalid mappings
+	m := newChannelTestModule()
+	for _, mapping := range [][]int{
+		make([]int, 65),
+		{64},
+		{-2},
+		{1},
+	} {
+		if err := m.RemapChannels(mapping); err == nil {
+			t.Errorf("Module.RemapChannels(%v) did not return error", mapping)
+		}
+	}
+
+	// test valid mapping
+	if err := m.RemapChannels([]int{-1, 7, 5, 3, 4, 0, 6, 1}); err != nil {
+		t.Fatalf("Module.RemapChannels() returned error: %v", err)
+	}
+	p := m.Patterns[0]
+	if got, want := p.Rows[1][5].Note, NoteOff; got != want {
+		t.Errorf("Pattern.Rows[1][5].Note == %v; want %v", got, want)
+	}
+	if got, want := p.Rows[0][0].VolPan, uint8(32); got != want {
+		t.Errorf("Pattern.Rows[0][0].VolPan == %v; want %v", got, want)
+	}
+	if got := p.Rows[0][2]; !got.IsEmpty() {
+		t.Errorf("Pattern.Rows[0][2] == %+v; want empty", got)
+	}
+	if got, want := m.ChannelPanning[:8],
+		[]uint8{5, 7, 32, 3, 4, 2, 6, 1}; !bytes.Equal(got, want) {
+		t.Errorf("Module.ChannelPanning[:8] == %v; want %v", got, want)
+	}
+	if got, want := m.ChannelVolume[7], uint8(63); got != want {
+		t.Errorf("Module.ChannelVolume[7] == %v; want %v", got, want)
+	}
+	if got, want := m.ChannelNames[0], "bass"; got != want {
+		t.Errorf("Module.ChannelNames[0] == %#v; want %#v", got, want)
+	}
+}
+
+func TestCompactChannels(t *testing.T) {
+	m := newChannelTestModule()
+	mapping := m.CompactChannels()
+	if got, want := mapping[:6], []int{2, 3, 0, 4, 5, 1}; !reflect.DeepEqual(
+		got, want) {
+		t.Errorf("Module.CompactChannels()[:6] == %v; want %v", got, want)
+	}
+	if got, want := m.UsedChannels(), []int{0, 1}; !reflect.DeepEqual(got,
+		want) {
+		t.Errorf("Module.UsedChannels() == %v; want %v", got, want)
+	}
+	if got, want := m.ChannelNames[:2], []string{"lead", "bass"}; !reflect.
+		DeepEqual(got, want) {
+		t.Errorf("Module.ChannelNames[:2] == %v; want %v", got, want)
+	}
+	if got, want := m.ChannelPanning[63], uint8(63); got != want {
+		t.Errorf("Module.ChannelPanning[63] == %v; want %v", got, want)
+	}
+}
+
+func TestReadChannelNames(t *testing.T) {
+	data := []byte("PNAM\x04\x00\x00\x00abcdCNAM\x28\x00\x00\x00")
+	data = append(data, "first\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"+
+		"\x00\x00\x00\x00second\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"+
+		"\x00\x00\x00"...)
+	names := readChannelNames(bytes.NewReader(data))
+	if got, want := len(names), 64; got != want {
+		t.Fatalf("len(names) == %v; want %v", got, want)
+	}
+	if got, want := names[:3], []string{"first", "second", ""}; !reflect.
+		DeepEqual(got, want) {
+		t.Errorf("names[:3] == %#v; want %#v", got, want)
+	}
+	if names := readChannelNames(bytes.NewReader(squareITS)); names != nil {
+		t.Errorf("readChannelNames(squareITS) == %#v; want nil", names)
+	}
+
+	// blocks longer than the rest of the data are ignored
+	for _, data := range [][]byte{
+		[]byte("CNAM\xf0\xff\xff\xfffirst"),
+		[]byte("PNAM\xf0\xff\xff\xffCNAM\x14\x00\x00\x00first"),
+	} {
+		if names := readChannelNames(bytes.NewReader(data)); names != nil {
+			t.Errorf("readChannelNames(%q) == %#v; want nil", data, names)
+		}
+	}
+
+	// names past the 64th channel are ignored
+	data = append([]byte("CNAM\x00\x10\x00\x00"), make([]byte, 0x1000)...)
+	copy(data[8+63*20:], "last")
+	names = readChannelNames(bytes.NewReader(data))
+	if len(names) != 64 || names[63] != "last" {
+		t.Errorf("names with long CNAM block == %#v", names)
+	}
+}
@@ -0,0 +1,259 @@
+package impulse
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// clipboardHeader is the first line of ModPlug Tracker clipboard text for IT
+// pattern data.
+const clipboardHeader = "ModPlug Tracker  IT"
+
+var noteNames = [12]string{
+	"C-", "C#", "D-", "D#", "E-", "F-", "F#", "G-", "G#", "A-", "A#", "B-",
+}
+
+// volume column effects, in order of their IT volume column ranges
+var volPanRanges = []struct {
+	letter     byte
+	first, max uint8
+}{
+	{'v', 0, 64},
+	{'a', 65, 9},
+	{'b', 75, 9},
+	{'c', 85, 9},
+	{'d', 95, 9},
+	{'e', 105, 9},
+	{'f', 115, 9},
+	{'p', 128, 64},
+	{'g', 193, 9},
+	{'h', 203, 9},
+}
+
+func formatCell(c Cell) string {
+	b := make([]byte, 0, 11)
+
+	switch {
+	case c.Note < 120:
+		b = append(b, noteNames[c.Note%12]...)
+		b = append(b, '0'+c.Note/12)
+	case c.Note == NoteNone:
+		b = append(b, "..."...)
+	case c.Note == NoteCut:
+		b = append(b, "^^^"...)
+	case c.Note == NoteOff:
+		b = append(b, "==="...)
+	default:
+		b = append(b, "~~~"...)
+	}
+
+	if c.Instrument != 0 && c.Instrument < 100 {
+		b = append(b, fmt.Sprintf("%02d", c.Instrument)...)
+	} else {
+		b = append(b, ".."...)
+	}
+
+	vol := "..."
+	for _, r := range volPanRanges {
+		if c.VolPan >= r.first && c.VolPan <= r.first+r.max {
+			vol = fmt.Sprintf("%c%02d", r.letter, c.VolPan-r.first)
+			break
+		}
+	}
+	b = append(b, vol...)
+
+	if c.Command != 0 && c.Command <= 26 {
+		b = append(b, '@'+c.Command)
+	} else {
+		b = append(b, '.')
+	}
+	if c.Command != 0 || c.Parameter != 0 {
+		b = append(b, fmt.Sprintf("%02X", c.Parameter)...)
+	} else {
+		b = append(b, ".."...)
+	}
+
+	return string(b)
+}
+
+// WriteClipboard writes a block of pattern data to w in the text format that
+// ModPlug Tracker, OpenMPT, and Schism Tracker use for copying IT pattern data
+// to the clipboard.
+func WriteClipboard(w io.Writer, block [][]Cell) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(clipboardHeader + "\r\n")
+	for _, row := range block {
+		for _, c := range row {
+			bw.WriteByte('|')
+			bw.WriteString(formatCell(c))
+		}
+		bw.WriteString("\r\n")
+	}
+	return bw.Flush()
+}
+
+// parseDecimal parses a two-digit decimal field.
+func parseDecimal(s string) (uint8, bool) {
+	if len(s) != 2 || s[0] < '0' || s[0] > '9' || s[1] < '0' || s[1] > '9' {
+		return 0, false
+	}
+	return (s[0]-'0')*10 + s[1] - '0', true
+}
+
+// parseHex parses a two-digit hexadecimal field.
+func parseHex(s string) (uint8, bool) {
+	var v uint8
+	if len(s) != 2 {
+		return 0, false
+	}
+	for i := 0; i < 2; i++ {
+		c := s[i]
+		switch {
+		case c >= '0' && c <= '9':
+			v = v<<4 | (c - '0')
+		case c >= 'A' && c <= 'F':
+			v = v<<4 | (c - 'A' + 10)
+		case c >= 'a' && c <= 'f':
+			v = v<<4 | (c - 'a' + 10)
+		default:
+			return 0, false
+		}
+	}
+	return v, true
+}
+
+// blank returns true if a field contains no data.
+func blank(s string) bool {
+	return strings.Trim(s, ". ") == ""
+}
+
+func parseCell(s string) (Cell, error) {
+	c := EmptyCell
+
+	// pad missing fields so that partial selections parse
+	if len(s) < 11 {
+		s += strings.Repeat(" ", 11-len(s))
+	}
+	note, ins, vol, cmd, param := s[0:3], s[3:5], s[5:8], s[8:9], s[9:11]
+
+	switch {
+	case blank(note):
+	case note == "^^^":
+		c.Note = NoteCut
+	case note == "===":
+		c.Note = NoteOff
+	case note == "~~~":
+		c.Note = NoteFade
+	default:
+		ok := false
+		for i, name := range noteNames {
+			if note[:2] == name && note[2] >= '0' && note[2] <= '9' {
+				c.Note, ok = uint8(i)+(note[2]-'0')*12, true
+				break
+			}
+		}
+		if !ok {
+			return c, fmt.Errorf("invalid note %q", note)
+		}
+	}
+
+	if !blank(ins) {
+		v, ok := parseDecimal(ins)
+		if !ok {
+			return c, fmt.Errorf("invalid instrument %q", ins)
+		}
+		c.Instrument = v
+	}
+
+	if !blank(vol) {
+		v, ok := parseDecimal(vol[1:])
+		found := false
+		for _, r := range volPanRanges {
+			if vol[0] == r.letter {
+				if v > r.max {
+					v = r.max
+				}
+				c.VolPan, found = r.first+v, true
+				break
+			}
+		}
+		if !ok || !found {
+			return c, fmt.Errorf("invalid volume column %q", vol)
+		}
+	}
+
+	if !blank(cmd) {
+		if cmd[0] < 'A' || cmd[0] > 'Z' {
+			return c, fmt.Errorf("invalid effect %q", cmd)
+		}
+		c.Command = cmd[0] - '@'
+	}
+	if !blank(param) {
+		v, ok := parseHex(param)
+		if !ok {
+			return c, fmt.Errorf("invalid effect parameter %q", param)
+		}
+		c.Parameter = v
+	}
+
+	return c, nil
+}
+
+// ReadClipboard reads a block of pattern data from r in ModPlug Tracker
+// clipboard format. All rows in the returned block have the same number of
+// channels; rows with fewer channels than the widest row are padded with
+// empty cells.
+func ReadClipboard(r io.Reader) ([][]Cell, error) {
+	s := bufio.NewScanner(r)
+	if !s.Scan() {
+		if err := s.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("clipboard data is empty")
+	}
+	header := strings.Fields(s.Text())
+	if len(header) < 3 || header[0] != "ModPlug" || header[1] != "Tracker" ||
+		header[2] != "IT" {
+		return nil, errors.New("data is not ModPlug Tracker IT clipboard text")
+	}
+
+	var block [][]Cell
+	width := 0
+	for s.Scan() {
+		line := strings.TrimRight(s.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "|")
+		if fields[0] != "" {
+			return nil, fmt.Errorf("invalid clipboard row %q", line)
+		}
+		row := make([]Cell, len(fields)-1)
+		for i, field := range fields[1:] {
+			var err error
+			if row[i], err = parseCell(field); err != nil {
+				return nil, fmt.Errorf("row %d, channel %d: %v", len(block),
+					i, err)
+			}
+		}
+		if len(row) > width {
+			width = len(row)
+		}
+		block = append(block, row)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, row := range block {
+		for len(row) < width {
+			row = append(row, EmptyCell)
+		}
+		block[i] = row
+	}
+
+	return block, nil
+}
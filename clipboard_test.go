@@ -0,0 +1,101 @@
+package impulse
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var testClipboard = "ModPlug Tracker  IT\r\n" +
+	"|C-501v64A06|...........\r\n" +
+	"|===........|^^^..p32H4F\r\n" +
+	"|~~~99a09...|G#9..g05SB1\r\n"
+
+var testBlock = [][]Cell{
+	{
+		{Note: 60, Instrument: 1, VolPan: 64, Command: 1, Parameter: 6},
+		EmptyCell,
+	},
+	{
+		{Note: NoteOff, VolPan: VolPanNone},
+		{Note: NoteCut, VolPan: 160, Command: 8, Parameter: 0x4f},
+	},
+	{
+		{Note: NoteFade, Instrument: 99, VolPan: 74},
+		{Note: 116, VolPan: 198, Command: 19, Parameter: 0xb1},
+	},
+}
+
+func TestReadClipboard(t *testing.T) {
+	// test invalid reads
+	for _, s := range []string{
+		"",
+		"not clipboard data\r\n|C-501.........\r\n",
+		"ModPlug Tracker  IT\r\n|H-501.........\r\n",
+		"ModPlug Tracker  IT\r\n|C-5AA.........\r\n",
+		"ModPlug Tracker  IT\r\n|C-501x01......\r\n",
+		"ModPlug Tracker  IT\r\n|C-501...AZZ\r\n",
+		"ModPlug Tracker  IT\r\nC-501......\r\n",
+	} {
+		if _, err := ReadClipboard(strings.NewReader(s)); err == nil {
+			t.Errorf("ReadClipboard(%q) did not return error", s)
+		}
+	}
+
+	// test valid read
+	block, err := ReadClipboard(strings.NewReader(testClipboard))
+	if err != nil {
+		t.Fatalf("ReadClipboard() returned error: %v", err)
+	}
+	if got, want := len(block), len(testBlock); got != want {
+		t.Fatalf("len(block) == %v; want %v", got, want)
+	}
+	for i := range block {
+		if got, want := len(block[i]), len(testBlock[i]); got != want {
+			t.Fatalf("len(block[%d]) == %v; want %v", i, got, want)
+		}
+		for j := range block[i] {
+			if got, want := block[i][j], testBlock[i][j]; got != want {
+				t.Errorf("block[%d][%d] == %+v; want %+v", i, j, got, want)
+			}
+		}
+	}
+
+	// test partial and ragged rows
+	block, err = ReadClipboard(strings.NewReader(
+		"ModPlug Tracker IT\n|C-5\n|D-5|E-5\n"))
+	if err != nil {
+		t.Fatalf("ReadClipboard() returned error: %v", err)
+	}
+	if got, want := block[0][1], EmptyCell; got != want {
+		t.Errorf("block[0][1] == %+v; want %+v", got, want)
+	}
+	if got, want := block[1][1].Note, uint8(64); got != want {
+		t.Errorf("block[1][1].Note == %v; want %v", got, want)
+	}
+}
+
+func TestWriteClipboard(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteClipboard(&b, testBlock); err != nil {
+		t.Fatalf("WriteClipboard() returned error: %v", err)
+	}
+	if got, want := b.String(), testClipboard; got != want {
+		t.Errorf("WriteClipboard() wrote %q; want %q", got, want)
+	}
+}
+
+func TestPatternBlock(t *testing.T) {
+	p := NewPattern(32)
+	p.Paste(testBlock, 30, 63)
+	block := p.Block(30, 63, 3, 2)
+	if got, want := len(block), 2; got != want {
+		t.Fatalf("len(block) == %v; want %v", got, want)
+	}
+	if got, want := len(block[0]), 1; got != want {
+		t.Fatalf("len(block[0]) == %v; want %v", got, want)
+	}
+	if got, want := block[1][0], testBlock[1][0]; got != want {
+		t.Errorf("block[1][0] == %+v; want %+v", got, want)
+	}
+}
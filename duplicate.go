@@ -0,0 +1,149 @@
+package impulse
+
+// PatternMerge records a set of duplicate patterns merged by
+// Module.MergeDuplicatePatterns. Pattern indices refer to Module.Patterns as
+// it was before the merge.
+type PatternMerge struct {
+	Canonical  int   // index of the pattern that was kept
+	NewIndex   int   // index of the kept pattern after the merge
+	Duplicates []int // indices of the patterns that were removed
+}
+
+// patternKey returns a string that is identical for two patterns if and only
+// if the first n rows of their contents are identical.
+func patternKey(p *Pattern, n int) string {
+	rows := p.Rows[:n]
+	b := make([]byte, 0, 2+len(rows)*64*5)
+	b = append(b, byte(len(rows)), byte(len(rows)>>8))
+	for i := range rows {
+		for _, c := range rows[i] {
+			b = append(b, c.Note, c.Instrument, c.VolPan, c.Command,
+				c.Parameter)
+		}
+	}
+	return string(b)
+}
+
+// playedRows returns the number of rows of p that can be played. Trailing
+// empty rows are never reached if the last non-empty row always ends the
+// pattern and no pattern break in the module targets them. maxBreak is the
+// highest row targeted by a pattern break in the module.
+func playedRows(p *Pattern, maxBreak int) int {
+	n := len(p.Rows)
+	for n > 0 && rowIsEmpty(&p.Rows[n-1]) {
+		n--
+	}
+	if n > maxBreak && rowEndsPattern(&p.Rows[n-1]) {
+		return n
+	}
+	return len(p.Rows)
+}
+
+// rowEndsPattern returns true if playback always leaves the pattern after row,
+// because it contains a position jump or pattern break and no pattern loop.
+func rowEndsPattern(row *[64]Cell) bool {
+	ends := false
+	for _, c := range row {
+		switch c.Command {
+		case effectJump, effectBreak:
+			ends = true
+		case effectS:
+			// S00 may repeat an SBx command
+			if c.Parameter>>4 == 0xb || c.Parameter == 0 {
+				return false
+			}
+		}
+	}
+	return ends
+}
+
+func rowIsEmpty(row *[64]Cell) bool {
+	for _, c := range row {
+		if !c.IsEmpty() {
+			return false
+		}
+	}
+	return true
+}
+
+// MergeDuplicatePatterns finds patterns with identical contents, rewrites
+// OrderList to refer only to the first pattern in each set of duplicates, and
+// removes the other patterns from Patterns. If ignoreTrailing is true,
+// patterns that differ only in their number of trailing empty rows are also
+// considered duplicates, if those rows are never played because the pattern
+// ends before them. Merging never changes the length of the song. The
+// returned slice describes each merge performed, in order of canonical
+// pattern index.
+func (m *Module) MergeDuplicatePatterns(ignoreTrailing bool) []PatternMerge {
+	maxBreak := 0
+	if ignoreTrailing {
+		for _, p := range m.Patterns {
+			if p == nil {
+				continue
+			}
+			for i := range p.Rows {
+				for _, c := range p.Rows[i] {
+					if c.Command == effectBreak && int(c.Parameter) > maxBreak {
+						maxBreak = int(c.Parameter)
+					}
+				}
+			}
+		}
+	}
+
+	var merges []PatternMerge
+	mergeIndex := make(map[string]int) // pattern key -> index in merges
+	canonical := make([]int, len(m.Patterns))
+
+	for i, p := range m.Patterns {
+		canonical[i] = i
+		if p == nil {
+			continue
+		}
+		n := len(p.Rows)
+		if ignoreTrailing {
+			n = playedRows(p, maxBreak)
+		}
+		key := patternKey(p, n)
+		if j, ok := mergeIndex[key]; ok {
+			merges[j].Duplicates = append(merges[j].Duplicates, i)
+			canonical[i] = merges[j].Canonical
+		} else {
+			mergeIndex[key] = len(merges)
+			merges = append(merges, PatternMerge{Canonical: i})
+		}
+	}
+
+	// compute new indices and remove duplicates
+	newIndex := make([]int, len(m.Patterns))
+	patterns := m.Patterns[:0]
+	for i, p := range m.Patterns {
+		if canonical[i] == i {
+			newIndex[i] = len(patterns)
+			patterns = append(patterns, p)
+		} else {
+			newIndex[i] = newIndex[canonical[i]]
+		}
+	}
+	for i := len(patterns); i < len(m.Patterns); i++ {
+		m.Patterns[i] = nil
+	}
+	m.Patterns = patterns
+
+	// references to nonexistent patterns are left unchanged
+	for i, v := range m.OrderList {
+		if int(v) < len(newIndex) {
+			m.OrderList[i] = uint8(newIndex[v])
+		}
+	}
+
+	// only report sets that actually contained duplicates
+	report := merges[:0]
+	for _, merge := range merges {
+		if len(merge.Duplicates) > 0 {
+			merge.NewIndex = newIndex[merge.Canonical]
+			report = append(report, merge)
+		}
+	}
+	return report
+}
@@ -0,0 +1,104 @@
+package impulse
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestMergeDuplicatePatterns(t *testing.T) {
+	newModule := func() *Module {
+		a, b, short := NewPattern(64), NewPattern(64), NewPattern(32)
+		a.Rows[0][0] = Cell{Note: 60, Instrument: 1, VolPan: VolPanNone}
+		b.Rows[0][0] = Cell{Note: 62, Instrument: 1, VolPan: VolPanNone}
+		short.Rows[0][0] = a.Rows[0][0]
+		a2 := NewPattern(64)
+		a2.Paste(a.Block(0, 0, 64, 64), 0, 0)
+
+		// patterns that end with a break before their trailing empty rows
+		end, end2 := NewPattern(64), NewPattern(32)
+		for _, p := range []*Pattern{end, end2} {
+			p.Rows[0][0] = a.Rows[0][0]
+			p.Rows[1][1] = Cell{VolPan: VolPanNone, Command: effectBreak}
+		}
+		return &Module{
+			OrderList: []uint8{0, 1, 2, 254, 3, 4, 5, 6, 200, 255},
+			Patterns: []*Pattern{a, b, a2, short, NewPattern(64), end,
+				end2},
+		}
+	}
+
+	// test exact merge
+	m := newModule()
+	report := m.MergeDuplicatePatterns(false)
+	want := []PatternMerge{{Canonical: 0, NewIndex: 0, Duplicates: []int{2}}}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("MergeDuplicatePatterns(false) == %+v; want %+v", report, want)
+	}
+	if got, want := len(m.Patterns), 6; got != want {
+		t.Errorf("len(Module.Patterns) == %v; want %v", got, want)
+	}
+	order := []uint8{0, 1, 0, 254, 2, 3, 4, 5, 200, 255}
+	if got := m.OrderList; !bytes.Equal(got, order) {
+		t.Errorf("Module.OrderList == %v; want %v", got, order)
+	}
+
+	// test merge ignoring trailing empty rows, which only merges patterns
+	// whose trailing rows are never played
+	m = newModule()
+	report = m.MergeDuplicatePatterns(true)
+	want = []PatternMerge{
+		{Canonical: 0, NewIndex: 0, Duplicates: []int{2}},
+		{Canonical: 5, NewIndex: 4, Duplicates: []int{6}},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("MergeDuplicatePatterns(true) == %+v; want %+v", report, want)
+	}
+	if got, want := len(m.Patterns), 5; got != want {
+		t.Errorf("len(Module.Patterns) == %v; want %v", got, want)
+	}
+	order = []uint8{0, 1, 0, 254, 2, 3, 4, 4, 200, 255}
+	if got := m.OrderList; !bytes.Equal(got, order) {
+		t.Errorf("Module.OrderList == %v; want %v", got, order)
+	}
+
+	// test no-op
+	if report := m.MergeDuplicatePatterns(true); len(report) != 0 {
+		t.Errorf("MergeDuplicatePatterns(true) == %+v; want none", report)
+	}
+
+	// a pattern break to a trailing row makes it reachable
+	m = newModule()
+	m.Patterns[1].Rows[63][0] = Cell{VolPan: VolPanNone,
+		Command: effectBreak, Parameter: 40}
+	if report := m.MergeDuplicatePatterns(true); len(report) != 1 {
+		t.Errorf("MergeDuplicatePatterns(true) with C28 == %+v; want 1 merge",
+			report)
+	}
+}
+
+func TestMergeDuplicatePatternsDuration(t *testing.T) {
+	for _, ignoreTrailing := range []bool{false, true} {
+		for _, breakRow := range []uint8{0, 40} {
+			m := &Module{OrderList: []uint8{0, 1, 2, 3, 4, 5, 6, 7}}
+			for _, n := range []int{64, 32, 200, 32, 64, 32} {
+				m.Patterns = append(m.Patterns, NewPattern(n))
+			}
+			m.Patterns[0].Rows[0][0] = Cell{Note: 60, VolPan: VolPanNone}
+			m.Patterns[1].Rows[0][0] = m.Patterns[0].Rows[0][0]
+			for _, p := range m.Patterns[4:] {
+				p.Rows[3][0] = Cell{VolPan: VolPanNone, Command: effectBreak,
+					Parameter: breakRow}
+			}
+			want := m.Duration()
+			m.MergeDuplicatePatterns(ignoreTrailing)
+			got := m.Duration()
+			if got.Total != want.Total ||
+				!reflect.DeepEqual(got.Orders, want.Orders) {
+				t.Errorf("Duration() after MergeDuplicatePatterns(%v) with "+
+					"C%02X == %v; want %v", ignoreTrailing, breakRow,
+					got.Orders, want.Orders)
+			}
+		}
+	}
+}
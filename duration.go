@@ -0,0 +1,131 @@
+package impulse
+
+import "time"
+
+// Effect commands that affect playback timing.
+const (
+	effectSpeed = 'A' - '@'
+	effectTempo = 'T' - '@'
+)
+
+// SongDuration describes the playback time of a Module.
+type SongDuration struct {
+	Total     time.Duration   // time until playback first loops
+	LoopStart Position        // position that playback loops back to
+	LoopTime  time.Duration   // time at which LoopStart is first played
+	Orders    []time.Duration // total time spent in each order
+}
+
+// timer tracks the speed and tempo of a module during playback.
+type timer struct {
+	speed, tempo int
+	tempoMemory  [64]uint8
+	sMemory      [64]uint8
+
+	rate      int   // sample rate used to count frames, or zero
+	frames    int64 // frames played so far at rate
+	frameFrac float64
+}
+
+func newTimer(m *Module) *timer {
+	t := &timer{speed: int(m.InitialSpeed), tempo: int(m.InitialTempo)}
+	if t.speed == 0 {
+		t.speed = 6
+	}
+	if t.tempo < 32 {
+		t.tempo = 125
+	}
+	return t
+}
+
+// row returns the duration of a row containing the given cells, and updates
+// the speed and tempo accordingly.
+func (t *timer) row(cells *[64]Cell) time.Duration {
+	rowDelay, tickDelay := -1, 0
+	var slides []int
+	for ch, c := range cells {
+		switch c.Command {
+		case effectSpeed:
+			if c.Parameter != 0 {
+				t.speed = int(c.Parameter)
+			}
+		case effectTempo:
+			param := c.Parameter
+			if param == 0 {
+				param = t.tempoMemory[ch]
+			}
+			t.tempoMemory[ch] = param
+			switch {
+			case param >= 0x20:
+				t.tempo = int(param)
+			case param >= 0x10:
+				slides = append(slides, int(param&0xf))
+			default:
+				slides = append(slides, -int(param))
+			}
+		case effectS:
+			param := c.Parameter
+			if param == 0 {
+				param = t.sMemory[ch]
+			}
+			t.sMemory[ch] = param
+			switch param >> 4 {
+			case 0x6:
+				tickDelay += int(param & 0xf)
+			case 0xe:
+				if rowDelay < 0 {
+					rowDelay = int(param & 0xf)
+				}
+			}
+		}
+	}
+	if rowDelay < 0 {
+		rowDelay = 0
+	}
+
+	// ticks after the first tick of each row repetition slide the tempo
+	var seconds float64
+	ticks := t.speed*(rowDelay+1) + tickDelay
+	for tick := 0; tick < ticks; tick++ {
+		if tick%t.speed != 0 || tick >= t.speed*(rowDelay+1) {
+			for _, slide := range slides {
+				t.tempo += slide
+			}
+			if t.tempo < 32 {
+				t.tempo = 32
+			} else if t.tempo > 255 {
+				t.tempo = 255
+			}
+		}
+		seconds += 2.5 / float64(t.tempo)
+		if t.rate > 0 {
+			// same rounding as the player, which carries over the fractional
+			// part of each tick
+			frames := float64(t.rate)*2.5/float64(t.tempo) + t.frameFrac
+			t.frames += int64(frames)
+			t.frameFrac = frames - float64(int64(frames))
+		}
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// Duration simulates playback of m until the song loops, and returns the time
+// that playback takes.
+func (m *Module) Duration() SongDuration {
+	d := SongDuration{Orders: make([]time.Duration, len(m.OrderList))}
+	t := newTimer(m)
+	firstPlayed := make(map[Position]time.Duration)
+	it := m.Iterate(0)
+	for it.Next() {
+		pos := it.Position()
+		if _, ok := firstPlayed[pos]; !ok {
+			firstPlayed[pos] = d.Total
+		}
+		rowTime := t.row(it.Cells())
+		d.Orders[pos.Order] += rowTime
+		d.Total += rowTime
+	}
+	d.LoopStart = it.Position()
+	d.LoopTime = firstPlayed[d.LoopStart]
+	return d
+}
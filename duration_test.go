@@ -0,0 +1,87 @@
+package impulse
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// approxEqual returns true if two durations are within a microsecond.
+func approxEqual(a, b time.Duration) bool {
+	d := a - b
+	return d > -time.Microsecond && d < time.Microsecond
+}
+
+func TestDuration(t *testing.T) {
+	// test module read from file; order 0 refers to a nonexistent pattern, so
+	// 64 empty rows are played at speed 6, tempo 125
+	m, err := ReadModule(bytes.NewReader(testIT))
+	if err != nil {
+		t.Fatalf("ReadModule() returned error: %v", err)
+	}
+	d := m.Duration()
+	if got, want := d.Total, 7680*time.Millisecond; !approxEqual(got, want) {
+		t.Errorf("SongDuration.Total == %v; want %v", got, want)
+	}
+	if got, want := d.LoopStart, (Position{0, 1, 0}); got != want {
+		t.Errorf("SongDuration.LoopStart == %v; want %v", got, want)
+	}
+
+	// test speed, tempo, tempo slide, and row delay effects
+	intro, body := NewPattern(32), NewPattern(32)
+	intro.Rows[0][0] = Cell{Note: NoteNone, VolPan: VolPanNone,
+		Command: effectSpeed, Parameter: 3}
+	intro.Rows[0][1] = Cell{Note: NoteNone, VolPan: VolPanNone,
+		Command: effectTempo, Parameter: 150}
+	intro.Rows[1][0] = Cell{Note: NoteNone, VolPan: VolPanNone,
+		Command: effectTempo, Parameter: 0x1a} // 150 -> 160 -> 170
+	intro.Rows[2][0] = Cell{Note: NoteNone, VolPan: VolPanNone,
+		Command: effectTempo, Parameter: 0} // 170 -> 180 -> 190
+	intro.Rows[3][0] = Cell{Note: NoteNone, VolPan: VolPanNone,
+		Command: effectS, Parameter: 0xe1} // 6 ticks
+	intro.Rows[3][1] = Cell{Note: NoteNone, VolPan: VolPanNone,
+		Command: effectS, Parameter: 0x62} // 8 ticks
+	intro.Rows[4][0] = Cell{Note: NoteNone, VolPan: VolPanNone,
+		Command: effectTempo, Parameter: 125}
+	intro.Rows[5][1] = Cell{Note: NoteNone, VolPan: VolPanNone,
+		Command: effectS, Parameter: 0} // S62 from memory, 5 ticks
+	body.Rows[31][0] = Cell{Note: NoteNone, VolPan: VolPanNone,
+		Command: effectJump, Parameter: 1}
+	m = &Module{
+		InitialSpeed: 6,
+		InitialTempo: 125,
+		OrderList:    []uint8{0, 1, OrderEnd},
+		Patterns:     []*Pattern{intro, body},
+	}
+	d = m.Duration()
+
+	seconds := func(tempos ...int) (s float64) {
+		for _, tempo := range tempos {
+			s += 2.5 / float64(tempo)
+		}
+		return
+	}
+	intro0 := seconds(150, 150, 150) + seconds(150, 160, 170) +
+		seconds(170, 180, 190) + seconds(190, 190, 190, 190, 190, 190, 190,
+		190) + seconds(125, 125, 125)*27 + seconds(125, 125, 125, 125, 125)
+	body0 := seconds(125, 125, 125) * 32
+	toDuration := func(s float64) time.Duration {
+		return time.Duration(s * float64(time.Second))
+	}
+
+	if got, want := d.Orders[0], toDuration(intro0); !approxEqual(got, want) {
+		t.Errorf("SongDuration.Orders[0] == %v; want %v", got, want)
+	}
+	if got, want := d.Orders[1], toDuration(body0); !approxEqual(got, want) {
+		t.Errorf("SongDuration.Orders[1] == %v; want %v", got, want)
+	}
+	if got, want := d.Total, toDuration(intro0+body0); !approxEqual(got, want) {
+		t.Errorf("SongDuration.Total == %v; want %v", got, want)
+	}
+	if got, want := d.LoopStart, (Position{1, 1, 0}); got != want {
+		t.Errorf("SongDuration.LoopStart == %v; want %v", got, want)
+	}
+	if got, want := d.LoopTime, toDuration(intro0); !approxEqual(got, want) {
+		t.Errorf("SongDuration.LoopTime == %v; want %v", got, want)
+	}
+}
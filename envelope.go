@@ -0,0 +1,130 @@
+package impulse
+
+// IsFilter returns true if e is a pitch envelope that controls the filter
+// cutoff instead of the pitch.
+func (e *Envelope) IsFilter() bool {
+	return e.Flags&EnvelopeUseFilter != 0
+}
+
+// ValueAt returns the value of e at the given tick, linearly interpolated
+// between nodes. Loops are not taken into account. If e has no nodes, ValueAt
+// returns zero.
+func (e *Envelope) ValueAt(tick int) float64 {
+	nodes := e.NodePoints
+	if len(nodes) == 0 {
+		return 0
+	}
+	for i := 0; i+1 < len(nodes); i++ {
+		a, b := nodes[i], nodes[i+1]
+		if tick < int(b.Tick) {
+			if tick <= int(a.Tick) || b.Tick <= a.Tick {
+				return float64(a.Value)
+			}
+			frac := float64(tick-int(a.Tick)) / float64(int(b.Tick)-int(a.Tick))
+			return float64(a.Value) + frac*float64(int(b.Value)-int(a.Value))
+		}
+	}
+	return float64(nodes[len(nodes)-1].Value)
+}
+
+// nodeTick returns the tick of node i, clamped to the nodes of e.
+func (e *Envelope) nodeTick(i uint8) int {
+	if int(i) >= len(e.NodePoints) {
+		i = uint8(len(e.NodePoints) - 1)
+	}
+	return int(e.NodePoints[i].Tick)
+}
+
+// EnvelopeIterator evaluates an Envelope one tick at a time, as during
+// playback of a note. The sustain loop is active until Release is called.
+// Values are in the range of the envelope's nodes: 0->64 for volume envelopes,
+// and -32->32 for panning, pitch, and filter envelopes.
+//
+// The zero value is an iterator that has ended; use Reset or Envelope.Iterate
+// to start one.
+type EnvelopeIterator struct {
+	env      *Envelope
+	tick     int
+	value    float64
+	released bool
+	ended    bool
+}
+
+// Iterate returns an EnvelopeIterator positioned at the start of e, with the
+// key held down. The EnvelopeOn flag is not checked.
+func (e *Envelope) Iterate() *EnvelopeIterator {
+	it := &EnvelopeIterator{}
+	it.Reset(e)
+	return it
+}
+
+// Reset restarts it at the start of e, with the key held down. If e is nil or
+// has no nodes, the iterator has already ended.
+func (it *EnvelopeIterator) Reset(e *Envelope) {
+	*it = EnvelopeIterator{env: e}
+	if e == nil || len(e.NodePoints) == 0 {
+		it.ended = true
+		return
+	}
+	it.value = float64(e.NodePoints[0].Value)
+}
+
+// Next returns the value of the envelope at the current tick and advances to
+// the next tick. After the envelope ends, Next keeps returning the value of
+// the last node.
+func (it *EnvelopeIterator) Next() float64 {
+	e := it.env
+	if e == nil || len(e.NodePoints) == 0 {
+		return 0
+	}
+	it.value = e.ValueAt(it.tick)
+	if it.ended {
+		return it.value
+	}
+	it.tick++
+
+	switch {
+	case !it.released && e.Flags&EnvelopeSusLoopOn != 0:
+		if it.tick > e.nodeTick(e.SusLoopEnd) {
+			it.tick = e.nodeTick(e.SusLoopBegin)
+		}
+	case e.Flags&EnvelopeLoopOn != 0:
+		if it.tick > e.nodeTick(e.LoopEnd) {
+			it.tick = e.nodeTick(e.LoopBegin)
+		}
+	default:
+		last := int(e.NodePoints[len(e.NodePoints)-1].Tick)
+		if it.tick > last {
+			it.tick = last
+			it.ended = true
+		}
+	}
+	return it.value
+}
+
+// Release releases the key, exiting the sustain loop.
+func (it *EnvelopeIterator) Release() {
+	it.released = true
+}
+
+// Released returns true if Release has been called since the last Reset.
+func (it *EnvelopeIterator) Released() bool {
+	return it.released
+}
+
+// Value returns the value returned by the last call to Next, or the value of
+// the first node if Next has not been called.
+func (it *EnvelopeIterator) Value() float64 {
+	return it.value
+}
+
+// Tick returns the envelope tick that the next call to Next will evaluate.
+func (it *EnvelopeIterator) Tick() int {
+	return it.tick
+}
+
+// Ended returns true if the envelope has passed its last node with no active
+// loop. An ended volume envelope with a final value of zero ends the note.
+func (it *EnvelopeIterator) Ended() bool {
+	return it.ended || it.env == nil
+}
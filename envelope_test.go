@@ -0,0 +1,112 @@
+package impulse
+
+import "testing"
+
+func testEnvelope(flags EnvelopeFlag) *Envelope {
+	return &Envelope{
+		Flags: EnvelopeOn | flags,
+		NodePoints: []NodePoint{
+			{Value: 0, Tick: 0}, {Value: 32, Tick: 4}, {Value: 64, Tick: 6},
+			{Value: 16, Tick: 8},
+		},
+		LoopBegin:    1,
+		LoopEnd:      2,
+		SusLoopBegin: 0,
+		SusLoopEnd:   1,
+	}
+}
+
+// next returns the next n values of it.
+func next(it *EnvelopeIterator, n int) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = it.Next()
+	}
+	return values
+}
+
+func checkValues(t *testing.T, name string, got, want []float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: got %d values; want %d", name, len(got), len(want))
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("%s: values == %v; want %v", name, got, want)
+			return
+		}
+	}
+}
+
+func TestEnvelopeValueAt(t *testing.T) {
+	e := testEnvelope(0)
+	for _, c := range []struct {
+		tick int
+		want float64
+	}{
+		{-1, 0}, {0, 0}, {2, 16}, {4, 32}, {5, 48}, {7, 40}, {8, 16}, {99, 16},
+	} {
+		if got := e.ValueAt(c.tick); got != c.want {
+			t.Errorf("ValueAt(%d) == %v; want %v", c.tick, got, c.want)
+		}
+	}
+	if got := (&Envelope{}).ValueAt(0); got != 0 {
+		t.Errorf("ValueAt(0) with no nodes == %v; want 0", got)
+	}
+}
+
+func TestEnvelopeIterator(t *testing.T) {
+	// no loops
+	it := testEnvelope(0).Iterate()
+	checkValues(t, "no loop", next(it, 10),
+		[]float64{0, 8, 16, 24, 32, 48, 64, 40, 16, 16})
+	if !it.Ended() {
+		t.Errorf("envelope not ended after last node")
+	}
+
+	// sustain loop, then release into the regular loop
+	it = testEnvelope(EnvelopeSusLoopOn | EnvelopeLoopOn).Iterate()
+	checkValues(t, "sustain", next(it, 7),
+		[]float64{0, 8, 16, 24, 32, 0, 8})
+	it.Release()
+	if !it.Released() {
+		t.Errorf("Released() == false after Release")
+	}
+	checkValues(t, "release", next(it, 7),
+		[]float64{16, 24, 32, 48, 64, 32, 48})
+	if it.Ended() {
+		t.Errorf("looping envelope ended")
+	}
+
+	// sustain loop only
+	it = testEnvelope(EnvelopeSusLoopOn).Iterate()
+	next(it, 20)
+	if it.Ended() {
+		t.Errorf("sustained envelope ended")
+	}
+	it.Release()
+	next(it, 20)
+	if !it.Ended() || it.Value() != 16 {
+		t.Errorf("released envelope: ended %v, value %v; want true, 16",
+			it.Ended(), it.Value())
+	}
+
+	// empty envelopes
+	var zero EnvelopeIterator
+	if !zero.Ended() || zero.Next() != 0 {
+		t.Errorf("zero EnvelopeIterator not ended")
+	}
+	it.Reset(nil)
+	if !it.Ended() || it.Next() != 0 {
+		t.Errorf("EnvelopeIterator of nil envelope not ended")
+	}
+}
+
+func TestEnvelopeIsFilter(t *testing.T) {
+	if testEnvelope(0).IsFilter() {
+		t.Errorf("IsFilter() == true without EnvelopeUseFilter")
+	}
+	if !testEnvelope(EnvelopeUseFilter).IsFilter() {
+		t.Errorf("IsFilter() == false with EnvelopeUseFilter")
+	}
+}
@@ -85,6 +85,7 @@ const (
 	EnvelopeOn EnvelopeFlag = 1 << iota
 	EnvelopeLoopOn
 	EnvelopeSusLoopOn
+	EnvelopeCarry     = 0x08 // OpenMPT extension, ignored by Impulse Tracker
 	EnvelopeUseFilter = 0x80
 )
 
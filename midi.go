@@ -0,0 +1,66 @@
+package impulse
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// length of a macro in embedded MIDI configuration data
+const macroLength = 32
+
+// MIDIConfig is the MIDI macro configuration of a module. Macros are strings
+// of hexadecimal digits and parameter letters; see the Impulse Tracker
+// documentation for details.
+type MIDIConfig struct {
+	// MIDI start, stop, tick, note on, note off, volume change, pan change,
+	// bank change, and program change
+	Global [9]string
+
+	// parameterized macros selected by SF0-SFF and sent by Z00-Z7F
+	Parametric [16]string
+
+	// fixed macros sent by Z80-ZFF
+	Fixed [128]string
+}
+
+// DefaultMIDIConfig returns Impulse Tracker's default MIDI configuration,
+// which applies to modules without an embedded configuration. SF0 controls the
+// filter cutoff, and Z80-Z8F set the filter resonance.
+func DefaultMIDIConfig() *MIDIConfig {
+	c := &MIDIConfig{
+		Global: [9]string{"FF", "FC", "", "9c n v", "9c n 0", "", "", "",
+			"Cc p"},
+	}
+	c.Parametric[0] = "F0F000z"
+	for i := 0; i < 16; i++ {
+		c.Fixed[i] = fmt.Sprintf("F0F001%02X", i*8)
+	}
+	return c
+}
+
+// readMIDIConfig reads embedded MIDI configuration data from r.
+func readMIDIConfig(r io.Reader) (*MIDIConfig, error) {
+	p := make([]byte, macroLength*(9+16+128))
+	if _, err := io.ReadFull(r, p); err != nil {
+		return nil, err
+	}
+	macro := func(i int) string {
+		b := p[i*macroLength : (i+1)*macroLength]
+		if n := bytes.IndexByte(b, 0); n >= 0 {
+			b = b[:n]
+		}
+		return string(b)
+	}
+	c := &MIDIConfig{}
+	for i := range c.Global {
+		c.Global[i] = macro(i)
+	}
+	for i := range c.Parametric {
+		c.Parametric[i] = macro(len(c.Global) + i)
+	}
+	for i := range c.Fixed {
+		c.Fixed[i] = macro(len(c.Global) + len(c.Parametric) + i)
+	}
+	return c, nil
+}
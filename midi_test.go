@@ -0,0 +1,50 @@
+package impulse
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReadMIDIConfig(t *testing.T) {
+	data := make([]byte, macroLength*(9+16+128))
+	copy(data, "FF")
+	copy(data[macroLength*9:], "F0F000z")
+	copy(data[macroLength*(9+16+127):], "F0F001 7F\x00garbage")
+
+	c, err := readMIDIConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("readMIDIConfig() returned error: %v", err)
+	}
+	if got, want := c.Global[0], "FF"; got != want {
+		t.Errorf("Global[0] == %q; want %q", got, want)
+	}
+	if got, want := c.Parametric[0], "F0F000z"; got != want {
+		t.Errorf("Parametric[0] == %q; want %q", got, want)
+	}
+	if got, want := c.Fixed[127], "F0F001 7F"; got != want {
+		t.Errorf("Fixed[127] == %q; want %q", got, want)
+	}
+	if got := c.Fixed[0]; got != "" {
+		t.Errorf("Fixed[0] == %q; want empty", got)
+	}
+
+	if _, err := readMIDIConfig(bytes.NewReader(data[:100])); err == nil {
+		t.Errorf("readMIDIConfig() did not return error for short data")
+	}
+}
+
+func TestDefaultMIDIConfig(t *testing.T) {
+	c := DefaultMIDIConfig()
+	if got, want := c.Parametric[0], "F0F000z"; got != want {
+		t.Errorf("Parametric[0] == %q; want %q", got, want)
+	}
+	if got, want := c.Fixed[1], "F0F00108"; got != want {
+		t.Errorf("Fixed[1] == %q; want %q", got, want)
+	}
+	if got, want := c.Fixed[15], "F0F00178"; got != want {
+		t.Errorf("Fixed[15] == %q; want %q", got, want)
+	}
+	if got := c.Fixed[16]; got != "" {
+		t.Errorf("Fixed[16] == %q; want empty", got)
+	}
+}
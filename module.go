@@ -29,6 +29,21 @@ type rawModule struct {
 	ChnlVol       [64]byte
 }
 
+// ModuleFlag is a bit set of playback options stored in a Module's header.
+type ModuleFlag uint16
+
+// Module flags, as defined by Impulse Tracker.
+const (
+	StereoMixing         ModuleFlag = 1 << iota // stereo output, else mono
+	Vol0MixOptimizations                        // skip mixing silent voices
+	UseInstruments                              // instrument mode, else samples
+	LinearSlides                                // linear pitch slides
+	OldEffects                                  // old-style effect behavior
+	CompatibleGxx                               // Gxx shares Exx/Fxx memory
+	MIDIPitchController                         // MIDI pitch wheel control
+	EmbeddedMIDIConfig                          // MIDIConfig is stored in file
+)
+
 // Module is an Impulse Tracker module.
 type Module struct {
 	SongName        string // max 26 bytes
@@ -38,12 +53,18 @@ type Module struct {
 	InitialTempo    uint8
 	Separation      uint8 // range 0->128
 	PitchWheelDepth uint8
+	CreatedWith     uint16 // tracker version that created the file
+	CompatibleWith  uint16 // minimum tracker version compatible with the file
+	Flags           ModuleFlag
 	Message         string
-	ChannelPanning  []uint8 // range 0->64
-	ChannelVolume   []uint8 // range 0->64
-	OrderList       []uint8 // range 0->199, 254, 255
+	ChannelPanning  []uint8     // range 0->64
+	ChannelVolume   []uint8     // range 0->64
+	ChannelNames    []string    // max 20 bytes each; nil if not present
+	MIDIConfig      *MIDIConfig // nil if not embedded
+	OrderList       []uint8     // range 0->199, 254, 255
 	Samples         []*Sample
 	Instruments     []*Instrument
+	Patterns        []*Pattern
 }
 
 func moduleFromRaw(raw *rawModule, r io.ReadSeeker) (*Module, error) {
@@ -55,10 +76,15 @@ func moduleFromRaw(raw *rawModule, r io.ReadSeeker) (*Module, error) {
 		InitialTempo:    raw.IT,
 		Separation:      raw.Sep,
 		PitchWheelDepth: raw.PWD,
+		CreatedWith:     binary.LittleEndian.Uint16(raw.Cwtv[:]),
+		CompatibleWith:  binary.LittleEndian.Uint16(raw.Cmwt[:]),
+		Flags:           ModuleFlag(raw.Flags),
 		ChannelPanning:  make([]uint8, 64),
 		ChannelVolume:   make([]uint8, 64),
 		OrderList:       make([]uint8, raw.OrdNum),
 		Samples:         make([]*Sample, raw.SmpNum),
+		Instruments:     make([]*Instrument, raw.InsNum),
+		Patterns:        make([]*Pattern, raw.PatNum),
 	}
 
 	for i := range m.ChannelPanning {
@@ -83,6 +109,30 @@ func moduleFromRaw(raw *rawModule, r io.ReadSeeker) (*Module, error) {
 		m.Message = string(p)
 	}
 
+	// read header extensions
+	extOffset := 0xc0 + int64(raw.OrdNum) +
+		int64(raw.InsNum+raw.SmpNum+raw.PatNum)*4
+	if _, err := r.Seek(extOffset, 0); err != nil {
+		return nil, err
+	}
+	if raw.Special&0x0002 != 0 {
+		// skip edit history
+		var n uint16
+		if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+			return nil, err
+		}
+		if _, err := r.Seek(int64(n)*8, 1); err != nil {
+			return nil, err
+		}
+	}
+	if raw.Special&0x0008 != 0 {
+		var err error
+		if m.MIDIConfig, err = readMIDIConfig(r); err != nil {
+			return nil, err
+		}
+	}
+	m.ChannelNames = readChannelNames(r)
+
 	for i := range m.Samples {
 		var err error
 		ptrOffset := 0xc0 + int64(raw.OrdNum+raw.InsNum*4) + int64(i*4)
@@ -114,9 +164,84 @@ func moduleFromRaw(raw *rawModule, r io.ReadSeeker) (*Module, error) {
 		}
 	}
 
+	for i := range m.Patterns {
+		var err error
+		ptrOffset := 0xc0 + int64(raw.OrdNum) +
+			int64(raw.InsNum+raw.SmpNum)*4 + int64(i*4)
+		if _, err = r.Seek(ptrOffset, 0); err != nil {
+			return nil, err
+		}
+		var patOffset uint32
+		binary.Read(r, binary.LittleEndian, &patOffset)
+		if patOffset == 0 {
+			// a null pointer denotes an empty 64-row pattern
+			m.Patterns[i] = NewPattern(64)
+			continue
+		}
+		if _, err = r.Seek(int64(patOffset), 0); err != nil {
+			return nil, err
+		}
+		if m.Patterns[i], err = readPattern(r); err != nil {
+			return nil, err
+		}
+	}
+
 	return m, nil
 }
 
+// readChannelNames reads the optional pattern name and channel name blocks
+// written by OpenMPT and Schism Tracker, returning the channel names or nil if
+// there are none. Blocks that extend past the end of r are ignored.
+func readChannelNames(r io.ReadSeeker) []string {
+	pos, err := r.Seek(0, 1)
+	if err != nil {
+		return nil
+	}
+	end, err := r.Seek(0, 2)
+	if err != nil {
+		return nil
+	}
+	if _, err := r.Seek(pos, 0); err != nil {
+		return nil
+	}
+	for {
+		var block struct {
+			ID     [4]byte
+			Length uint32
+		}
+		if err := binary.Read(r, binary.LittleEndian, &block); err != nil {
+			return nil
+		}
+		pos += 8
+		id := string(block.ID[:])
+		if id != "PNAM" && id != "CNAM" || int64(block.Length) > end-pos {
+			return nil
+		}
+		if id == "PNAM" {
+			if pos, err = r.Seek(int64(block.Length), 1); err != nil {
+				return nil
+			}
+			continue
+		}
+		// names of channels past the 64th are ignored
+		n := block.Length
+		if n > 64*20 {
+			n = 64 * 20
+		}
+		p := make([]byte, n)
+		if _, err := io.ReadFull(r, p); err != nil {
+			return nil
+		}
+		names := make([]string, 64)
+		for i := range names {
+			if len(p) >= (i+1)*20 {
+				names[i] = string(bytes.Trim(p[i*20:(i+1)*20], "\x00"))
+			}
+		}
+		return names
+	}
+}
+
 // ReadModule reads a Module in IT format from r.
 func ReadModule(r io.ReadSeeker) (*Module, error) {
 	raw := new(rawModule)
@@ -67,6 +67,16 @@ func TestModule(t *testing.T) {
 	if got, want := m.PitchWheelDepth, uint8(12); got != want {
 		t.Errorf("Module.PitchWheelDepth == %v; want %v", got, want)
 	}
+	if got, want := m.CreatedWith, uint16(0x1822); got != want {
+		t.Errorf("Module.CreatedWith == %#x; want %#x", got, want)
+	}
+	if got, want := m.CompatibleWith, uint16(0x0214); got != want {
+		t.Errorf("Module.CompatibleWith == %#x; want %#x", got, want)
+	}
+	if got, want := m.Flags, StereoMixing|LinearSlides|
+		MIDIPitchController; got != want {
+		t.Errorf("Module.Flags == %v; want %v", got, want)
+	}
 	for i, v := range m.ChannelPanning {
 		if got, want := v, uint8(64-i); got != want {
 			t.Errorf("Module.ChannelPanning[%d] == %v; want %v", i, got, want)
@@ -81,4 +91,31 @@ func TestModule(t *testing.T) {
 	if got := m.OrderList; bytes.Compare(got, want) != 0 {
 		t.Errorf("Module.OrderList == %v; want %v", got, want)
 	}
+
+	// test pattern data
+	if got, want := len(m.Patterns), 1; got != want {
+		t.Fatalf("len(Module.Patterns) == %v; want %v", got, want)
+	}
+	p := m.Patterns[0]
+	if got, want := len(p.Rows), 32; got != want {
+		t.Fatalf("len(Pattern.Rows) == %v; want %v", got, want)
+	}
+	cells := []struct {
+		row  int
+		want Cell
+	}{
+		{0, Cell{Note: 52, Instrument: 1, VolPan: VolPanNone}},
+		{1, EmptyCell},
+		{2, Cell{Note: 50, Instrument: 1, VolPan: VolPanNone}},
+		{4, Cell{Note: 48, Instrument: 1, VolPan: VolPanNone}},
+		{14, Cell{Note: NoteOff, VolPan: VolPanNone}},
+	}
+	for _, c := range cells {
+		if got := p.Rows[c.row][0]; got != c.want {
+			t.Errorf("Pattern.Rows[%d][0] == %+v; want %+v", c.row, got, c.want)
+		}
+	}
+	if got := p.Rows[0][1]; !got.IsEmpty() {
+		t.Errorf("Pattern.Rows[0][1] == %+v; want empty", got)
+	}
 }
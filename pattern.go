@@ -0,0 +1,187 @@
+package impulse
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Special values for Cell.Note. Any other value in the range 120->252 is also
+// treated as note fade.
+const (
+	NoteFade uint8 = 246
+	NoteNone uint8 = 253
+	NoteCut  uint8 = 254
+	NoteOff  uint8 = 255
+)
+
+// VolPanNone is the value of Cell.VolPan for an empty volume column.
+const VolPanNone uint8 = 255
+
+// Cell is the data for one channel in one row of a Pattern.
+type Cell struct {
+	Note       uint8 // range 0->119 (C-0 -> B-9), 120->255 (see NoteNone)
+	Instrument uint8 // range 0->99 (0 = none)
+	VolPan     uint8 // range 0->212, VolPanNone
+	Command    uint8 // range 0->26 (0 = none, 1 = A, 2 = B, ...)
+	Parameter  uint8
+}
+
+// EmptyCell is a Cell containing no data.
+var EmptyCell = Cell{Note: NoteNone, VolPan: VolPanNone}
+
+// IsEmpty returns true if c contains no data.
+func (c Cell) IsEmpty() bool {
+	return c.Note == NoteNone && c.Instrument == 0 && c.VolPan == VolPanNone &&
+		c.Command == 0 && c.Parameter == 0
+}
+
+// Pattern is a block of note data in a Module.
+type Pattern struct {
+	Rows [][64]Cell // range 32->200 rows
+}
+
+// NewPattern returns a Pattern with the given number of empty rows.
+func NewPattern(rows int) *Pattern {
+	p := &Pattern{Rows: make([][64]Cell, rows)}
+	for i := range p.Rows {
+		for j := range p.Rows[i] {
+			p.Rows[i][j] = EmptyCell
+		}
+	}
+	return p
+}
+
+// Block returns a copy of the cells of p in the given range of rows and
+// channels. The range is clipped to the bounds of the pattern.
+func (p *Pattern) Block(row, channel, rows, channels int) [][]Cell {
+	if row < 0 {
+		rows, row = rows+row, 0
+	}
+	if channel < 0 {
+		channels, channel = channels+channel, 0
+	}
+	if row+rows > len(p.Rows) {
+		rows = len(p.Rows) - row
+	}
+	if channel+channels > 64 {
+		channels = 64 - channel
+	}
+	if rows <= 0 || channels <= 0 {
+		return nil
+	}
+	block := make([][]Cell, rows)
+	for i := range block {
+		block[i] = make([]Cell, channels)
+		copy(block[i], p.Rows[row+i][channel:channel+channels])
+	}
+	return block
+}
+
+// Paste copies block into p, with the first cell of the block at the given
+// row and channel. Cells that fall outside the pattern are discarded.
+func (p *Pattern) Paste(block [][]Cell, row, channel int) {
+	for i, cells := range block {
+		if row+i < 0 || row+i >= len(p.Rows) {
+			continue
+		}
+		for j, c := range cells {
+			if channel+j >= 0 && channel+j < 64 {
+				p.Rows[row+i][channel+j] = c
+			}
+		}
+	}
+}
+
+type rawPatternHeader struct {
+	Length uint16
+	Rows   uint16
+	_      uint32
+}
+
+var errPatternTruncated = errors.New("pattern data is truncated")
+
+// readPattern reads a packed pattern in IT format from r.
+func readPattern(r io.Reader) (*Pattern, error) {
+	var hdr rawPatternHeader
+	if err := binary.Read(r, binary.LittleEndian, &hdr); err != nil {
+		return nil, err
+	}
+	data := make([]byte, hdr.Length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	p := NewPattern(int(hdr.Rows))
+	var lastMask [64]uint8
+	var last [64]Cell
+	pos := 0
+	next := func() (byte, error) {
+		if pos >= len(data) {
+			return 0, errPatternTruncated
+		}
+		pos++
+		return data[pos-1], nil
+	}
+
+	for row := 0; row < len(p.Rows) && pos < len(data); {
+		chanVar, _ := next()
+		if chanVar == 0 {
+			row++
+			continue
+		}
+		ch := (chanVar - 1) & 63
+		if chanVar&0x80 != 0 {
+			mask, err := next()
+			if err != nil {
+				return nil, err
+			}
+			lastMask[ch] = mask
+		}
+		mask := lastMask[ch]
+		c := &p.Rows[row][ch]
+
+		var err error
+		if mask&0x01 != 0 {
+			if c.Note, err = next(); err != nil {
+				return nil, err
+			}
+			last[ch].Note = c.Note
+		}
+		if mask&0x02 != 0 {
+			if c.Instrument, err = next(); err != nil {
+				return nil, err
+			}
+			last[ch].Instrument = c.Instrument
+		}
+		if mask&0x04 != 0 {
+			if c.VolPan, err = next(); err != nil {
+				return nil, err
+			}
+			last[ch].VolPan = c.VolPan
+		}
+		if mask&0x08 != 0 {
+			if c.Command, err = next(); err != nil {
+				return nil, err
+			}
+			if c.Parameter, err = next(); err != nil {
+				return nil, err
+			}
+			last[ch].Command, last[ch].Parameter = c.Command, c.Parameter
+		}
+		if mask&0x10 != 0 {
+			c.Note = last[ch].Note
+		}
+		if mask&0x20 != 0 {
+			c.Instrument = last[ch].Instrument
+		}
+		if mask&0x40 != 0 {
+			c.VolPan = last[ch].VolPan
+		}
+		if mask&0x80 != 0 {
+			c.Command, c.Parameter = last[ch].Command, last[ch].Parameter
+		}
+	}
+
+	return p, nil
+}
@@ -0,0 +1,95 @@
+package impulse
+
+import "math"
+
+// amigaConst converts between Amiga periods and frequencies in Hz.
+const amigaConst = 1712 * 8363
+
+// NoteFrequency returns the playback frequency in Hz of a note in the range
+// 0->119 (C-0 -> B-9). C-5 plays at the sample's speed.
+func (s *Sample) NoteFrequency(note uint8) float64 {
+	return float64(s.Speed) * math.Exp2((float64(note)-60)/12)
+}
+
+// FrequencyNote returns the note, with a fractional part, that plays s at the
+// given frequency in Hz. It is the inverse of NoteFrequency.
+func (s *Sample) FrequencyNote(freq float64) float64 {
+	return 60 + 12*math.Log2(freq/float64(s.Speed))
+}
+
+// FrequencyToPeriod converts a frequency in Hz to an Amiga period, as used for
+// pitch slides when a module does not use linear slides.
+func FrequencyToPeriod(freq float64) float64 {
+	return amigaConst / freq
+}
+
+// PeriodToFrequency converts an Amiga period to a frequency in Hz.
+func PeriodToFrequency(period float64) float64 {
+	return amigaConst / period
+}
+
+// Transpose returns freq raised by the given number of 1/64 semitones.
+func Transpose(freq, delta float64) float64 {
+	return freq * math.Exp2(delta/768)
+}
+
+// SlideFrequency returns freq after a pitch slide by the given amount, which
+// is in 1/64 semitones if linear is true, and in Amiga period units otherwise.
+// Positive amounts raise the pitch. As in IT, Amiga periods do not slide below
+// 1, and a frequency of zero stays zero.
+func SlideFrequency(freq float64, amount int, linear bool) float64 {
+	if freq <= 0 {
+		return freq
+	}
+	if linear {
+		return Transpose(freq, float64(amount))
+	}
+	period := FrequencyToPeriod(freq) - float64(amount)
+	if period < 1 {
+		period = 1
+	}
+	return PeriodToFrequency(period)
+}
+
+// PitchSlideAmount returns the amount by which an Exx or Fxx effect with the
+// given parameter slides pitch on the first tick or other ticks of a row, in
+// the units of SlideFrequency. The amount is positive; Exx slides down and Fxx
+// slides up. EFx and FFx are fine slides and EEx and FEx are extra fine
+// slides, which apply only on the first tick.
+func PitchSlideAmount(param uint8, firstTick bool) int {
+	switch {
+	case param >= 0xf0:
+		if firstTick {
+			return int(param&0xf) * 4
+		}
+	case param >= 0xe0:
+		if firstTick {
+			return int(param & 0xf)
+		}
+	default:
+		if !firstTick {
+			return int(param) * 4
+		}
+	}
+	return 0
+}
+
+// Portamento returns freq after one tick of a Gxx tone portamento with the
+// given speed toward target. The result does not pass the target.
+func Portamento(freq, target float64, speed uint8, linear bool) float64 {
+	if freq <= 0 || target <= 0 {
+		return freq
+	}
+	if freq < target {
+		freq = SlideFrequency(freq, int(speed)*4, linear)
+		if freq > target {
+			freq = target
+		}
+	} else if freq > target {
+		freq = SlideFrequency(freq, -int(speed)*4, linear)
+		if freq < target {
+			freq = target
+		}
+	}
+	return freq
+}
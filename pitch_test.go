@@ -0,0 +1,103 @@
+package impulse
+
+import (
+	"math"
+	"testing"
+)
+
+// near returns true if a and b are within a small relative tolerance.
+func near(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(math.Abs(a), math.Abs(b))
+}
+
+func TestNoteFrequency(t *testing.T) {
+	s := &Sample{Speed: 8363}
+	for _, c := range []struct {
+		note uint8
+		want float64
+	}{
+		{60, 8363}, {72, 16726}, {48, 4181.5}, {0, 8363.0 / 32},
+	} {
+		if got := s.NoteFrequency(c.note); !near(got, c.want) {
+			t.Errorf("NoteFrequency(%d) == %v; want %v", c.note, got, c.want)
+		}
+		got := s.FrequencyNote(c.want)
+		if math.Abs(got-float64(c.note)) > 1e-9 {
+			t.Errorf("FrequencyNote(%v) == %v; want %d", c.want, got, c.note)
+		}
+	}
+
+	if got := FrequencyToPeriod(8363); got != 1712 {
+		t.Errorf("FrequencyToPeriod(8363) == %v; want 1712", got)
+	}
+	if got := PeriodToFrequency(856); got != 16726 {
+		t.Errorf("PeriodToFrequency(856) == %v; want 16726", got)
+	}
+	if got := Transpose(8363, 768); !near(got, 16726) {
+		t.Errorf("Transpose(8363, 768) == %v; want 16726", got)
+	}
+}
+
+func TestSlideFrequency(t *testing.T) {
+	// linear slides are in 1/64 semitones
+	if got := SlideFrequency(8363, 64*12, true); !near(got, 16726) {
+		t.Errorf("linear slide up an octave == %v; want 16726", got)
+	}
+	if got := SlideFrequency(8363, -64*12, true); !near(got, 4181.5) {
+		t.Errorf("linear slide down an octave == %v; want 4181.5", got)
+	}
+
+	// Amiga slides are in period units
+	if got := SlideFrequency(8363, 856, false); !near(got, 16726) {
+		t.Errorf("Amiga slide up 856 periods == %v; want 16726", got)
+	}
+	got, want := SlideFrequency(8363, 9999, false), float64(amigaConst)
+	if got != want {
+		t.Errorf("Amiga slide past period 1 == %v; want %v", got, want)
+	}
+	if got := SlideFrequency(0, 64, true); got != 0 {
+		t.Errorf("slide of zero frequency == %v; want 0", got)
+	}
+}
+
+func TestPitchSlideAmount(t *testing.T) {
+	for _, c := range []struct {
+		param       uint8
+		first, rest int
+	}{
+		{0x10, 0, 64},
+		{0xdf, 0, 0xdf * 4},
+		{0xf4, 16, 0},
+		{0xe4, 4, 0},
+		{0xf0, 0, 0},
+	} {
+		if got := PitchSlideAmount(c.param, true); got != c.first {
+			t.Errorf("PitchSlideAmount(%#x, true) == %d; want %d",
+				c.param, got, c.first)
+		}
+		if got := PitchSlideAmount(c.param, false); got != c.rest {
+			t.Errorf("PitchSlideAmount(%#x, false) == %d; want %d",
+				c.param, got, c.rest)
+		}
+	}
+}
+
+func TestPortamento(t *testing.T) {
+	target := 16726.0
+	freq := 8363.0
+	ticks := 0
+	for freq != target {
+		freq = Portamento(freq, target, 16, true)
+		if freq > target {
+			t.Fatalf("portamento passed target: %v > %v", freq, target)
+		}
+		ticks++
+	}
+	// 16*4 units per tick is one semitone
+	if ticks != 12 {
+		t.Errorf("portamento took %d ticks; want 12", ticks)
+	}
+	if got := Portamento(target, 8363, 0xff, false); got != 8363 {
+		t.Errorf("Amiga portamento down == %v; want 8363", got)
+	}
+}
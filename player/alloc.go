@@ -0,0 +1,105 @@
+package player
+
+import "github.com/jangler/impulse"
+
+// release applies a duplicate check action or S70-S72 past note action to v.
+func (v *voice) release(p *Player, action impulse.DuplicateCheckAction) {
+	switch action {
+	case impulse.DuplicateCheckCut:
+		p.cutVoice(v)
+	case impulse.DuplicateCheckNoteOff:
+		v.noteOff()
+	case impulse.DuplicateCheckNoteFade:
+		v.noteFade()
+	}
+}
+
+// newVoice applies the new note action of c's current voice and the
+// duplicate check of the new note's instrument, then returns a voice for the
+// new note, or nil if no voice is available.
+func (p *Player) newVoice(c *channel, ins *impulse.Instrument,
+	s *impulse.Sample, note uint8) *voice {
+	if old := c.voice; old != nil && old.active {
+		action := impulse.NewNoteCut
+		if old.ins != nil {
+			action = old.ins.NewNoteAction
+		}
+		if c.nnaOverride >= 0 {
+			action = impulse.NewNoteAction(c.nnaOverride)
+		}
+		switch action {
+		case impulse.NewNoteCut:
+			p.cutVoice(old)
+		case impulse.NewNoteOff:
+			old.noteOff()
+		case impulse.NewNoteFade:
+			old.noteFade()
+		}
+		old.foreground = false
+	}
+	c.voice = nil
+	c.nnaOverride = -1
+
+	if ins != nil && ins.DuplicateCheckType != impulse.DuplicateCheckOff {
+		p.duplicateCheck(c, ins, s, note)
+	}
+	return p.freeVoice()
+}
+
+// duplicateCheck applies the duplicate check action of ins to c's background
+// voices that play the same instrument and match the new note by note,
+// sample, or instrument.
+func (p *Player) duplicateCheck(c *channel, ins *impulse.Instrument,
+	s *impulse.Sample, note uint8) {
+	for i := range p.voices {
+		v := &p.voices[i]
+		if !v.active || v.foreground || v.channel != c.index || v.ins != ins {
+			continue
+		}
+		switch ins.DuplicateCheckType {
+		case impulse.DuplicateCheckNote:
+			if v.note != note {
+				continue
+			}
+		case impulse.DuplciateCheckSample:
+			if v.sample != s {
+				continue
+			}
+		case impulse.DuplicateCheckInstrument:
+		default:
+			continue
+		}
+		v.release(p, ins.DuplicateCheckAction)
+	}
+}
+
+// freeVoice returns an inactive voice, or cuts and returns the quietest
+// background voice if all voices are in use. It returns nil if no voice is
+// available.
+func (p *Player) freeVoice() *voice {
+	var quietest *voice
+	for i := range p.voices {
+		v := &p.voices[i]
+		if !v.active {
+			return v
+		}
+		if !v.foreground && (quietest == nil ||
+			v.lvol+v.rvol < quietest.lvol+quietest.rvol) {
+			quietest = v
+		}
+	}
+	if quietest != nil {
+		p.cutVoice(quietest)
+	}
+	return quietest
+}
+
+// pastNoteAction applies an S70-S72 action to c's background voices.
+func (p *Player) pastNoteAction(c *channel, action uint8) {
+	for i := range p.voices {
+		v := &p.voices[i]
+		if v.active && !v.foreground && v.channel == c.index {
+			v.release(p, impulse.DuplicateCheckAction(action))
+		}
+	}
+}
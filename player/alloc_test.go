@@ -0,0 +1,88 @@
+package player
+
+import (
+	"testing"
+
+	"github.com/jangler/impulse"
+)
+
+// activeVoices returns the number of active voices in p.
+func activeVoices(p *Player) int {
+	n := 0
+	for i := range p.voices {
+		if p.voices[i].active {
+			n++
+		}
+	}
+	return n
+}
+
+func TestDuplicateCheck(t *testing.T) {
+	for _, c := range []struct {
+		dct    impulse.DuplicateCheckType
+		dca    impulse.DuplicateCheckAction
+		active int // active voices after three notes
+		fading int // fading voices after three notes
+	}{
+		{impulse.DuplicateCheckOff, impulse.DuplicateCheckCut, 3, 0},
+		{impulse.DuplicateCheckNote, impulse.DuplicateCheckCut, 2, 0},
+		{impulse.DuplicateCheckNote, impulse.DuplicateCheckNoteFade, 3, 1},
+		{impulse.DuplciateCheckSample, impulse.DuplicateCheckCut, 1, 0},
+		{impulse.DuplicateCheckInstrument, impulse.DuplicateCheckCut, 1, 0},
+	} {
+		m := stemModule()
+		ins := m.Instruments[0]
+		ins.FadeOut = 1
+		ins.DuplicateCheckType = c.dct
+		ins.DuplicateCheckAction = c.dca
+		rows := m.Patterns[0].Rows
+		rows[0][0] = cell(60, 1, impulse.VolPanNone, 0, 0)
+		rows[1][0] = cell(60, 1, impulse.VolPanNone, 0, 0)
+		rows[2][0] = cell(62, 1, impulse.VolPanNone, 0, 0)
+		rows[4][0] = impulse.EmptyCell
+		rows[0][1] = impulse.EmptyCell
+
+		p := New(m, testRate)
+		for i := 0; i < 13; i++ {
+			p.processTick()
+		}
+		fading := 0
+		for i := range p.voices {
+			if p.voices[i].active && p.voices[i].fading {
+				fading++
+			}
+		}
+		if got := activeVoices(p); got != c.active || fading != c.fading {
+			t.Errorf("DCT %d, DCA %d: %d active, %d fading; want %d, %d",
+				c.dct, c.dca, got, fading, c.active, c.fading)
+		}
+	}
+}
+
+func TestVoiceStealing(t *testing.T) {
+	m := stemModule()
+	p := New(m, testRate)
+	for i := range p.voices {
+		v := &p.voices[i]
+		v.active = true
+		v.lvol, v.rvol = 1, 1
+	}
+	p.voices[10].lvol = 0.5
+	p.voices[20].lvol, p.voices[20].rvol = 0.1, 0.1
+	p.voices[20].foreground = true
+	if got, want := p.freeVoice(), &p.voices[10]; got != want {
+		t.Errorf("stolen voice is not the quietest background voice")
+	}
+
+	if p.voices[10].active {
+		t.Errorf("stolen voice is still active")
+	}
+
+	p.voices[10].active = true
+	for i := range p.voices {
+		p.voices[i].foreground = true
+	}
+	if v := p.freeVoice(); v != nil {
+		t.Errorf("foreground voice stolen")
+	}
+}
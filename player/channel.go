@@ -0,0 +1,682 @@
+package player
+
+import "github.com/jangler/impulse"
+
+// effect commands
+const (
+	effectA = iota + 1
+	effectB
+	effectC
+	effectD
+	effectE
+	effectF
+	effectG
+	effectH
+	effectI
+	effectJ
+	effectK
+	effectL
+	effectM
+	effectN
+	effectO
+	effectP
+	effectQ
+	effectR
+	effectS
+	effectT
+	effectU
+	effectV
+	effectW
+	effectX
+	effectY
+	effectZ
+)
+
+// channel is the state of one of a module's 64 pattern channels.
+type channel struct {
+	index int
+	cell  impulse.Cell
+	voice *voice // foreground voice, or nil
+
+	note       uint8 // last note played, before keyboard table mapping
+	instrument uint8 // last instrument number
+	sample     *impulse.Sample
+
+	freq       float64 // Hz
+	volume     int     // range 0->64
+	chanVolume int     // range 0->64
+	pan        int     // range 0->64, panSurround
+	muted      bool
+	cutoff     int // range 0->127
+	resonance  int // range 0->127
+
+	// per-tick output modifiers
+	pitchDelta float64 // in 1/64 semitones
+	volDelta   int
+	panDelta   int
+	tremorOff  bool
+
+	// effect memory
+	volSlideMem     uint8
+	pitchSlideMem   uint8
+	portaMem        uint8
+	portaTarget     float64
+	vibSpeed        uint8
+	vibDepth        uint8
+	vibFine         bool
+	vibPos          int
+	vibWave         uint8
+	tremSpeed       uint8
+	tremDepth       uint8
+	tremPos         int
+	tremWave        uint8
+	panbSpeed       uint8
+	panbDepth       uint8
+	panbPos         int
+	panbWave        uint8
+	tremorMem       uint8
+	tremorCount     int
+	arpMem          uint8
+	retrigMem       uint8
+	retrigCount     int
+	offsetMem       uint8
+	highOffset      int
+	chanVolSlideMem uint8
+	panSlideMem     uint8
+	globalSlideMem  uint8
+	tempoMem        uint8
+	sMem            uint8
+	volColMem       uint8
+	nnaOverride     int   // -1 if none
+	sfx             uint8 // parametric macro selected by SFx
+
+	noteDelay int // tick on which to trigger the row's note, or -1
+	noteCut   int // tick on which to cut the note, or -1
+}
+
+// slideAmount returns the change made by a volume-slide-type effect parameter
+// on the first tick or other ticks of a row.
+func slideAmount(param uint8, firstTick bool) int {
+	hi, lo := int(param>>4), int(param&0xf)
+	switch {
+	case lo == 0xf && hi != 0:
+		if firstTick {
+			return hi
+		}
+	case hi == 0xf && lo != 0:
+		if firstTick {
+			return -lo
+		}
+	case lo == 0:
+		if !firstTick {
+			return hi
+		}
+	case hi == 0:
+		if !firstTick {
+			return -lo
+		}
+	}
+	return 0
+}
+
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+// memory returns param, or the value stored in mem if param is zero. The
+// value is then stored in mem.
+func memory(mem *uint8, param uint8) uint8 {
+	if param == 0 {
+		param = *mem
+	}
+	*mem = param
+	return param
+}
+
+// slidePitch changes c.freq by the given amount, in 1/64 semitones for linear
+// slides or in period units for Amiga slides.
+func (c *channel) slidePitch(p *Player, amount int) {
+	c.freq = impulse.SlideFrequency(c.freq, amount,
+		p.mod.Flags&impulse.LinearSlides != 0)
+}
+
+// pitchSlide processes an Exx or Fxx effect with the given direction.
+func (c *channel) pitchSlide(p *Player, param uint8, dir int, firstTick bool) {
+	if amount := impulse.PitchSlideAmount(param, firstTick); amount != 0 {
+		c.slidePitch(p, dir*amount)
+	}
+}
+
+// portamento slides c.freq toward c.portaTarget.
+func (c *channel) portamento(p *Player, param uint8) {
+	c.freq = impulse.Portamento(c.freq, c.portaTarget, param,
+		p.mod.Flags&impulse.LinearSlides != 0)
+}
+
+// waveValue returns the value of a vibrato-type waveform at a position.
+func (p *Player) waveValue(wave uint8, pos int) int {
+	if wave >= 3 {
+		return p.rng.Intn(129) - 64
+	}
+	return impulse.VibratoWaveform(wave).Value(pos)
+}
+
+func (c *channel) vibrato(p *Player) {
+	depth := float64(p.waveValue(c.vibWave, c.vibPos) * int(c.vibDepth))
+	if c.vibFine {
+		c.pitchDelta += depth / 64
+	} else {
+		c.pitchDelta += depth / 16
+	}
+	c.vibPos += int(c.vibSpeed) * 4
+}
+
+func (c *channel) tremolo(p *Player) {
+	c.volDelta += p.waveValue(c.tremWave, c.tremPos) * int(c.tremDepth) / 16
+	c.tremPos += int(c.tremSpeed) * 4
+}
+
+func (c *channel) panbrello(p *Player) {
+	c.panDelta += p.waveValue(c.panbWave, c.panbPos) * int(c.panbDepth) / 32
+	c.panbPos += int(c.panbSpeed)
+}
+
+// lookup returns the sample and mapped note for a note played with an
+// instrument number, or nil if there is no such sample.
+func (p *Player) lookup(ins uint8, note uint8) (*impulse.Instrument, int,
+	uint8) {
+	if p.mod.Flags&impulse.UseInstruments != 0 {
+		if int(ins) < 1 || int(ins) > len(p.mod.Instruments) ||
+			p.mod.Instruments[ins-1] == nil || note >= 120 {
+			return nil, -1, note
+		}
+		instrument := p.mod.Instruments[ins-1]
+		ns := instrument.KeyboardTable[note]
+		return instrument, int(ns.Sample) - 1, ns.Note
+	}
+	return nil, int(ins) - 1, note
+}
+
+// triggerNote processes the note, instrument, and volume column of the
+// channel's current cell.
+func (c *channel) triggerNote(p *Player) {
+	cell := c.cell
+	porta := cell.Command == effectG || cell.Command == effectL ||
+		(cell.VolPan >= 193 && cell.VolPan <= 202)
+
+	if cell.Instrument != 0 {
+		c.instrument = cell.Instrument
+	}
+
+	started := false
+	switch {
+	case cell.Note == impulse.NoteOff:
+		if c.voice != nil {
+			c.voice.noteOff()
+		}
+	case cell.Note == impulse.NoteCut:
+		if c.voice != nil {
+			p.cutVoice(c.voice)
+			c.voice = nil
+		}
+	case cell.Note >= 120 && cell.Note != impulse.NoteNone:
+		if c.voice != nil {
+			c.voice.noteFade()
+		}
+	case cell.Note < 120:
+		ins, smp, note := p.lookup(c.instrument, cell.Note)
+		if smp < 0 || smp >= len(p.mod.Samples) || p.mod.Samples[smp] == nil ||
+			p.samples[smp] == nil {
+			break
+		}
+		s := p.mod.Samples[smp]
+		freq := s.NoteFrequency(note)
+		if porta && c.voice != nil && c.voice.active {
+			c.portaTarget = freq
+			if cell.Instrument != 0 {
+				c.volume = int(s.DefaultVolume)
+				if p.quirks.portaSwitchesSample && s != c.voice.sample {
+					c.sample = s
+					c.voice.sample, c.voice.data = s, p.samples[smp]
+				}
+				if p.quirks.portaResetsEnvelopes {
+					c.voice.restartEnvelopes()
+				}
+			}
+			break
+		}
+
+		c.note = cell.Note
+		c.sample = s
+		c.freq = freq
+		c.portaTarget = freq
+		if cell.Instrument != 0 {
+			c.volume = int(s.DefaultVolume)
+			if ins != nil && ins.DefaultPanOn {
+				c.pan = int(ins.DefaultPan)
+			}
+			if s.DefaultPanOn {
+				c.pan = int(s.DefaultPan)
+			}
+		}
+		c.vibPos, c.tremPos = 0, 0
+
+		// save envelopes that may carry over before the old voice is reused
+		var carried [3]envelope
+		carry := p.quirks.envelopeCarry && ins != nil && c.voice != nil &&
+			c.voice.active && c.voice.ins == ins
+		if carry {
+			carried = [3]envelope{c.voice.volEnv, c.voice.panEnv,
+				c.voice.pitchEnv}
+		}
+
+		v := p.newVoice(c, ins, s, note)
+		if v == nil {
+			break
+		}
+		v.start(c.index, s, p.samples[smp], ins, c.instrument, note)
+		if carry {
+			v.volEnv.carry(&carried[0])
+			v.panEnv.carry(&carried[1])
+			v.pitchEnv.carry(&carried[2])
+		}
+		v.smpNum = uint8(smp + 1)
+		v.Interpolation = p.interp
+		c.voice = v
+		started = true
+		if p.quirks.retrigResetsOnNote {
+			c.retrigCount = 0
+		}
+
+		// instrument note properties
+		if ins != nil {
+			// IT sets bit 7 of the default filter values to enable them
+			if ins.DefaultCutoff < 0 {
+				c.cutoff = int(ins.DefaultCutoff & 0x7f)
+			}
+			if ins.DefaultResonance < 0 {
+				c.resonance = int(ins.DefaultResonance & 0x7f)
+			}
+			if ins.PitchPanSeparation != 0 && c.pan != panSurround {
+				c.pan = clamp(c.pan+(int(cell.Note)-int(ins.PitchPanCenter))*
+					int(ins.PitchPanSeparation)/8, 0, 64)
+			}
+			if ins.VolumeSwing != 0 {
+				swing := float64(ins.VolumeSwing) / 100
+				v.volSwing = 1 - swing*p.rng.Float64()
+			}
+			if ins.PanSwing != 0 && c.pan != panSurround {
+				swing := int(ins.PanSwing)
+				c.pan = clamp(c.pan+p.rng.Intn(swing*2+1)-swing, 0, 64)
+			}
+		}
+
+		// sample offset
+		if cell.Command == effectO {
+			offset := int(memory(&c.offsetMem, cell.Parameter))<<8 +
+				c.highOffset
+			if offset < len(v.data) {
+				v.pos = float64(offset)
+			}
+		}
+	default:
+		if cell.Instrument != 0 && c.sample != nil {
+			c.volume = int(c.sample.DefaultVolume)
+		}
+	}
+
+	// volume column set commands
+	switch vp := cell.VolPan; {
+	case vp <= 64:
+		c.volume = int(vp)
+	case vp >= 128 && vp <= 192:
+		c.pan = int(vp - 128)
+	}
+
+	if started {
+		p.event.Notes = append(p.event.Notes, NoteEvent{
+			Channel:    c.index,
+			Note:       cell.Note,
+			Instrument: c.instrument,
+			Volume:     c.volume,
+		})
+	}
+}
+
+// checkVoice clears c.voice if the voice has stopped or been reused.
+func (c *channel) checkVoice() {
+	if v := c.voice; v != nil &&
+		(!v.active || !v.foreground || v.channel != c.index) {
+		c.voice = nil
+	}
+}
+
+// processRow handles the start of a new row for c.
+func (c *channel) processRow(p *Player, cell impulse.Cell) {
+	c.checkVoice()
+	c.cell = cell
+	c.noteDelay, c.noteCut = -1, -1
+	param := cell.Parameter
+	if param == 0 {
+		param = c.sMem
+	}
+	if cell.Command == effectS && param>>4 == 0xd {
+		c.noteDelay = int(param & 0xf)
+		if c.noteDelay == 0 {
+			c.noteDelay = 1
+		}
+	} else {
+		c.triggerNote(p)
+	}
+	c.processEffects(p, 0)
+}
+
+// processTick handles a tick of the current row for c. tick is the tick
+// number within the row (or row repetition), and is zero for the first tick.
+func (c *channel) processTick(p *Player, tick int, rowTick int) {
+	c.checkVoice()
+	if rowTick == c.noteDelay {
+		c.triggerNote(p)
+		c.noteDelay = -1
+	}
+	if tick == c.noteCut && c.voice != nil {
+		c.volume = 0
+	}
+	c.processEffects(p, tick)
+}
+
+// processEffects processes the volume column and effect column of the
+// channel's current cell.
+func (c *channel) processEffects(p *Player, tick int) {
+	first := tick == 0
+	cell := c.cell
+	param := cell.Parameter
+	c.pitchDelta, c.volDelta, c.panDelta, c.tremorOff = 0, 0, 0, false
+
+	// volume column
+	if c.noteDelay < 0 {
+		switch vp := cell.VolPan; {
+		case vp >= 65 && vp <= 74: // a: fine volume up
+			if first {
+				x := int(memory(&c.volColMem, vp-65))
+				c.volume = clamp(c.volume+x, 0, 64)
+			}
+		case vp >= 75 && vp <= 84: // b: fine volume down
+			if first {
+				x := int(memory(&c.volColMem, vp-75))
+				c.volume = clamp(c.volume-x, 0, 64)
+			}
+		case vp >= 85 && vp <= 94: // c: volume slide up
+			x := memory(&c.volColMem, vp-85)
+			if !first {
+				c.volume = clamp(c.volume+int(x), 0, 64)
+			}
+		case vp >= 95 && vp <= 104: // d: volume slide down
+			x := memory(&c.volColMem, vp-95)
+			if !first {
+				c.volume = clamp(c.volume-int(x), 0, 64)
+			}
+		case vp >= 105 && vp <= 114: // e: pitch slide down
+			x := memory(&c.pitchSlideMem, (vp-105)*4)
+			if !first {
+				c.slidePitch(p, -int(x)*4)
+			}
+		case vp >= 115 && vp <= 124: // f: pitch slide up
+			x := memory(&c.pitchSlideMem, (vp-115)*4)
+			if !first {
+				c.slidePitch(p, int(x)*4)
+			}
+		case vp >= 193 && vp <= 202: // g: portamento
+			x := c.portaMemory(p, volColPorta[vp-193])
+			if !first {
+				c.portamento(p, x)
+			}
+		case vp >= 203 && vp <= 212: // h: vibrato
+			if vp > 203 {
+				c.vibDepth = vp - 203
+			}
+			c.vibFine = false
+			c.vibrato(p)
+		}
+	}
+
+	switch cell.Command {
+	case effectA:
+		if first && param != 0 {
+			p.speed = int(param)
+		}
+	case effectD:
+		param = memory(&c.volSlideMem, param)
+		c.volume = clamp(c.volume+slideAmount(param, first), 0, 64)
+	case effectE:
+		c.pitchSlide(p, memory(&c.pitchSlideMem, param), -1, first)
+	case effectF:
+		c.pitchSlide(p, memory(&c.pitchSlideMem, param), 1, first)
+	case effectG:
+		param = c.portaMemory(p, param)
+		if !first {
+			c.portamento(p, param)
+		}
+	case effectH, effectU:
+		if first {
+			if param>>4 != 0 {
+				c.vibSpeed = param >> 4
+			}
+			if param&0xf != 0 {
+				c.vibDepth = param & 0xf
+				c.vibFine = cell.Command == effectU
+			}
+		}
+		c.vibrato(p)
+	case effectI:
+		param = memory(&c.tremorMem, param)
+		on, off := int(param>>4), int(param&0xf)
+		if on == 0 {
+			on = 1
+		}
+		if off == 0 {
+			off = 1
+		}
+		c.tremorOff = c.tremorCount%(on+off) >= on
+		c.tremorCount++
+	case effectJ:
+		param = memory(&c.arpMem, param)
+		switch tick % 3 {
+		case 1:
+			c.pitchDelta += float64(param>>4) * 64
+		case 2:
+			c.pitchDelta += float64(param&0xf) * 64
+		}
+	case effectK:
+		param = memory(&c.volSlideMem, param)
+		c.volume = clamp(c.volume+slideAmount(param, first), 0, 64)
+		c.vibrato(p)
+	case effectL:
+		param = memory(&c.volSlideMem, param)
+		c.volume = clamp(c.volume+slideAmount(param, first), 0, 64)
+		if !first {
+			c.portamento(p, c.portaMemory(p, 0))
+		}
+	case effectM:
+		if first && param <= 64 {
+			c.chanVolume = int(param)
+		}
+	case effectN:
+		param = memory(&c.chanVolSlideMem, param)
+		c.chanVolume = clamp(c.chanVolume+slideAmount(param, first), 0, 64)
+	case effectP:
+		param = memory(&c.panSlideMem, param)
+		if c.pan != panSurround {
+			// low nibble slides right, high nibble slides left
+			c.pan = clamp(c.pan-slideAmount(param, first), 0, 64)
+		}
+	case effectQ:
+		param = memory(&c.retrigMem, param)
+		c.retrig(p, param)
+	case effectR:
+		if first {
+			if param>>4 != 0 {
+				c.tremSpeed = param >> 4
+			}
+			if param&0xf != 0 {
+				c.tremDepth = param & 0xf
+			}
+		}
+		c.tremolo(p)
+	case effectS:
+		if first {
+			c.effectS(p, memory(&c.sMem, param))
+		}
+	case effectT:
+		param = memory(&c.tempoMem, param)
+		switch {
+		case param >= 0x20:
+			if first {
+				p.tempo = int(param)
+			}
+		case !first && param >= 0x10:
+			p.tempo = clamp(p.tempo+int(param&0xf), 32, 255)
+		case !first:
+			p.tempo = clamp(p.tempo-int(param), 32, 255)
+		}
+	case effectV:
+		if first && param <= 128 {
+			p.globalVolume = int(param)
+		}
+	case effectW:
+		param = memory(&c.globalSlideMem, param)
+		p.globalVolume = clamp(p.globalVolume+slideAmount(param, first), 0,
+			128)
+	case effectX:
+		if first {
+			c.pan = int(param) >> 2
+			if param == 0xff {
+				c.pan = 64
+			}
+		}
+	case effectY:
+		if first {
+			if param>>4 != 0 {
+				c.panbSpeed = param >> 4
+			}
+			if param&0xf != 0 {
+				c.panbDepth = param & 0xf
+			}
+		}
+		c.panbrello(p)
+	case effectZ:
+		if first {
+			c.macro(p, param)
+		}
+	}
+}
+
+// portaMemory returns the parameter to use for a Gxx effect.
+func (c *channel) portaMemory(p *Player, param uint8) uint8 {
+	if p.mod.Flags&impulse.CompatibleGxx != 0 {
+		return memory(&c.pitchSlideMem, param)
+	}
+	return memory(&c.portaMem, param)
+}
+
+// retrig processes a Qxy effect.
+func (c *channel) retrig(p *Player, param uint8) {
+	interval := int(param & 0xf)
+	if interval == 0 {
+		interval = 1
+	}
+	c.retrigCount++
+	if c.retrigCount < interval {
+		return
+	}
+	c.retrigCount = 0
+
+	x := param >> 4
+	if m := retrigMul[x]; m[1] != 0 {
+		c.volume = clamp(c.volume*m[0]/m[1], 0, 64)
+	} else {
+		c.volume = clamp(c.volume+retrigAdd[x], 0, 64)
+	}
+	if c.voice != nil && c.voice.active {
+		c.voice.pos = 0
+		c.voice.reverse = false
+	}
+}
+
+// effectS processes an Sxy effect on the first tick of a row.
+func (c *channel) effectS(p *Player, param uint8) {
+	x := param & 0xf
+	switch param >> 4 {
+	case 0x3:
+		c.vibWave = x & 3
+	case 0x4:
+		c.tremWave = x & 3
+	case 0x5:
+		c.panbWave = x & 3
+	case 0x7:
+		switch {
+		case x <= 2:
+			p.pastNoteAction(c, x)
+		case x <= 6:
+			c.nnaOverride = int(x - 3)
+		case c.voice != nil:
+			switch x {
+			case 7:
+				c.voice.volEnv.setOn(false)
+			case 8:
+				c.voice.volEnv.setOn(true)
+			case 9:
+				c.voice.panEnv.setOn(false)
+			case 0xa:
+				c.voice.panEnv.setOn(true)
+			case 0xb:
+				c.voice.pitchEnv.setOn(false)
+			case 0xc:
+				c.voice.pitchEnv.setOn(true)
+			}
+		}
+	case 0x8:
+		c.pan = int(x) * 4
+	case 0x9:
+		if x == 1 {
+			c.pan = panSurround
+		}
+	case 0xa:
+		c.highOffset = int(x) << 16
+	case 0xc:
+		c.noteCut = int(x)
+		if c.noteCut == 0 {
+			c.noteCut = 1
+		}
+	case 0xf:
+		c.sfx = x
+	}
+}
+
+// updateVoice copies the channel's parameters to its foreground voice.
+func (c *channel) updateVoice() {
+	c.checkVoice()
+	v := c.voice
+	if v == nil {
+		return
+	}
+	v.freq = c.freq
+	v.pitchDelta = c.pitchDelta
+	v.volume = clamp(c.volume+c.volDelta, 0, 64)
+	if c.tremorOff {
+		v.volume = 0
+	}
+	v.chanVolume = c.chanVolume
+	v.pan = c.pan
+	if c.pan != panSurround {
+		v.pan = clamp(c.pan+c.panDelta, 0, 64)
+	}
+	v.muted = c.muted
+	v.cutoff, v.resonance = c.cutoff, c.resonance
+}
@@ -0,0 +1,132 @@
+package player
+
+import (
+	"sync/atomic"
+
+	"github.com/jangler/impulse"
+)
+
+// flagSet is a set of mute and solo flags, indexed by channel, instrument, or
+// sample number, that may be changed concurrently with rendering.
+type flagSet struct {
+	mute  [256]atomic.Bool
+	solo  [256]atomic.Bool
+	solos atomic.Int32 // number of solo flags set
+}
+
+func (f *flagSet) setMute(i int, mute bool) {
+	if i >= 0 && i < len(f.mute) {
+		f.mute[i].Store(mute)
+	}
+}
+
+func (f *flagSet) setSolo(i int, solo bool) {
+	if i < 0 || i >= len(f.solo) || f.solo[i].Swap(solo) == solo {
+		return
+	}
+	if solo {
+		f.solos.Add(1)
+	} else {
+		f.solos.Add(-1)
+	}
+}
+
+// audible returns false if i is muted, or if anything else is soloed.
+func (f *flagSet) audible(i int) bool {
+	if f.mute[i].Load() {
+		return false
+	}
+	return f.solos.Load() == 0 || f.solo[i].Load()
+}
+
+// controls are render-time overrides of a module's mixing.
+type controls struct {
+	channels, instruments, samples flagSet
+
+	// overrides of channel volume and panning, or -1 if none
+	volume [64]atomic.Int32
+	pan    [64]atomic.Int32
+}
+
+// reset clears the volume and panning overrides of c.
+func (c *controls) reset() {
+	for i := range c.volume {
+		c.volume[i].Store(-1)
+		c.pan[i].Store(-1)
+	}
+}
+
+// SetChannelMute mutes or unmutes a channel in the range 0->63. Muting a
+// channel also mutes the background voices of notes it started. Unlike the
+// mute flag in the module's channel panning, this does not change the
+// module, and may be called concurrently with rendering, as may the other
+// methods that control muting, soloing, volume, and panning.
+func (p *Player) SetChannelMute(ch int, mute bool) {
+	p.controls.channels.setMute(ch, mute)
+}
+
+// SetChannelSolo solos or unsolos a channel in the range 0->63. If any
+// channels are soloed, other channels are silent.
+func (p *Player) SetChannelSolo(ch int, solo bool) {
+	p.controls.channels.setSolo(ch, solo)
+}
+
+// SetInstrumentMute mutes or unmutes an instrument number. It has no effect
+// in sample mode.
+func (p *Player) SetInstrumentMute(ins int, mute bool) {
+	p.controls.instruments.setMute(ins, mute)
+}
+
+// SetInstrumentSolo solos or unsolos an instrument number. If any
+// instruments are soloed, other instruments are silent. It has no effect in
+// sample mode.
+func (p *Player) SetInstrumentSolo(ins int, solo bool) {
+	p.controls.instruments.setSolo(ins, solo)
+}
+
+// SetSampleMute mutes or unmutes a sample number.
+func (p *Player) SetSampleMute(smp int, mute bool) {
+	p.controls.samples.setMute(smp, mute)
+}
+
+// SetSampleSolo solos or unsolos a sample number. If any samples are soloed,
+// other samples are silent.
+func (p *Player) SetSampleSolo(smp int, solo bool) {
+	p.controls.samples.setSolo(smp, solo)
+}
+
+// SetChannelVolume overrides the channel volume of a channel in the range
+// 0->63 with a value in the range 0->64, replacing the module's initial
+// channel volume and any changes made by effects. A negative value removes
+// the override.
+func (p *Player) SetChannelVolume(ch, vol int) {
+	if ch >= 0 && ch < len(p.controls.volume) {
+		if vol >= 0 {
+			vol = clamp(vol, 0, 64)
+		}
+		p.controls.volume[ch].Store(int32(vol))
+	}
+}
+
+// SetChannelPanning overrides the panning of a channel in the range 0->63
+// with a value in the range 0->64, replacing the module's initial channel
+// panning and any changes made by effects or envelopes. A negative value
+// removes the override.
+func (p *Player) SetChannelPanning(ch, pan int) {
+	if ch >= 0 && ch < len(p.controls.pan) {
+		if pan >= 0 {
+			pan = clamp(pan, 0, 64)
+		}
+		p.controls.pan[ch].Store(int32(pan))
+	}
+}
+
+// audible returns true if v is not silenced by mute or solo controls.
+func (p *Player) audible(v *voice) bool {
+	if !p.controls.channels.audible(v.channel) ||
+		!p.controls.samples.audible(int(v.smpNum)) {
+		return false
+	}
+	return p.mod.Flags&impulse.UseInstruments == 0 ||
+		p.controls.instruments.audible(int(v.insNum))
+}
@@ -0,0 +1,90 @@
+package player
+
+import (
+	"math"
+	"testing"
+)
+
+// renderPlayer renders the rest of p's song and returns the output.
+func renderPlayer(p *Player) []float32 {
+	var out []float32
+	buf := make([]float32, 4096)
+	for {
+		n := p.Render(buf)
+		out = append(out, buf[:n*2]...)
+		if n < len(buf)/2 {
+			return out
+		}
+	}
+}
+
+// equalOutput returns true if a and b are nearly equal.
+func equalOutput(a, b []float32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(float64(a[i]-b[i])) > 1e-5 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestControls(t *testing.T) {
+	m := stemModule()
+	stems := renderStems(m, 2, StemsByChannel)
+	silence := make([]float32, len(stems[0]))
+
+	for _, c := range []struct {
+		name string
+		set  func(p *Player)
+		want []float32
+	}{
+		{"mute channel 1", func(p *Player) { p.SetChannelMute(1, true) },
+			stems[0]},
+		{"solo channel 1", func(p *Player) { p.SetChannelSolo(1, true) },
+			stems[1]},
+		{"unsolo channel 1", func(p *Player) {
+			p.SetChannelSolo(1, true)
+			p.SetChannelSolo(1, false)
+			p.SetChannelSolo(1, false)
+			p.SetChannelMute(0, true)
+		}, stems[1]},
+		{"mute instrument 2", func(p *Player) { p.SetInstrumentMute(2, true) },
+			stems[0]},
+		{"solo instrument 1", func(p *Player) { p.SetInstrumentSolo(1, true) },
+			stems[0]},
+		{"solo sample 2", func(p *Player) { p.SetSampleSolo(2, true) },
+			silence},
+		{"mute sample 1", func(p *Player) { p.SetSampleMute(1, true) },
+			silence},
+		{"channel 0 volume", func(p *Player) { p.SetChannelVolume(0, 0) },
+			stems[1]},
+	} {
+		p := New(m, testRate)
+		c.set(p)
+		if got := renderPlayer(p); !equalOutput(got, c.want) {
+			t.Errorf("%s: output does not match", c.name)
+		}
+	}
+
+	// panning override
+	p := New(m, testRate)
+	p.SetChannelSolo(0, true)
+	p.SetChannelPanning(0, 0)
+	if l, r := stereoPeaks(renderPlayer(p)); l == 0 || r != 0 {
+		t.Errorf("peaks with hard left panning == %v, %v; want > 0, 0", l, r)
+	}
+
+	// controls take effect on the next tick during playback, after the volume
+	// ramp
+	p = New(m, testRate)
+	buf := make([]float32, 2*rowFrames)
+	p.Render(buf)
+	p.SetChannelMute(0, true)
+	p.SetChannelMute(1, true)
+	if p.Render(buf); peak(buf[2*p.rampFrames:]) != 0 {
+		t.Errorf("output not silent after muting during playback")
+	}
+}
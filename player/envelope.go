@@ -0,0 +1,48 @@
+package player
+
+import "github.com/jangler/impulse"
+
+// envelope tracks playback of an instrument envelope for one voice.
+type envelope struct {
+	env   *impulse.Envelope
+	it    impulse.EnvelopeIterator
+	on    bool
+	value float64 // value at the last processed tick
+	ended bool    // true once the final node has been passed with no loop
+}
+
+// reset restarts e at the beginning of env.
+func (e *envelope) reset(env *impulse.Envelope) {
+	e.env = env
+	e.it.Reset(env)
+	e.setOn(env != nil && env.Flags&impulse.EnvelopeOn != 0)
+	e.value = e.it.Value()
+	e.ended = e.it.Ended()
+}
+
+// setOn turns e on or off, as by S7x. An envelope without nodes stays off.
+func (e *envelope) setOn(on bool) {
+	e.on = on && e.env != nil && len(e.env.NodePoints) > 0
+}
+
+// advance computes the envelope value for the current tick and moves to the
+// next tick. keyOn determines whether the sustain loop is active.
+func (e *envelope) advance(keyOn bool) {
+	if !e.on {
+		return
+	}
+	if !keyOn {
+		e.it.Release()
+	}
+	e.value = e.it.Next()
+	e.ended = e.it.Ended()
+}
+
+// carry continues e from the state of old, the same envelope of the previous
+// note, if the envelope has the carry flag and old is still held.
+func (e *envelope) carry(old *envelope) {
+	if e.env != nil && e.env.Flags&impulse.EnvelopeCarry != 0 &&
+		old.env == e.env && !old.it.Released() && !old.ended {
+		*e = *old
+	}
+}
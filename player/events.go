@@ -0,0 +1,53 @@
+package player
+
+import "github.com/jangler/impulse"
+
+// NoteEvent describes a note triggered during a tick.
+type NoteEvent struct {
+	Channel    int
+	Note       uint8 // range 0->119, before keyboard table mapping
+	Instrument uint8 // instrument number, or sample number in sample mode
+	Volume     int   // range 0->64, after the volume column
+}
+
+// Event describes a tick as it is processed during rendering. Tick 0 is the
+// start of a row.
+type Event struct {
+	Frame    int64 // offset of the tick in output frames from song start
+	Position impulse.Position
+	Tick     int
+	Speed    int
+	Tempo    int
+
+	// Cells contains the row being played, including effect commands such as
+	// Zxx or S0x that may be used as cue markers.
+	Cells *[64]impulse.Cell
+
+	// Notes contains the notes triggered on this tick, including notes
+	// delayed by SDx.
+	Notes []NoteEvent
+}
+
+// SetEventHandler sets a function to be called for each tick rendered by
+// Render, RenderStems, or a Reader, before the tick's audio is rendered. The
+// Event and its contents are only valid for the duration of the call. Ticks
+// skipped by seeking do not produce events, but the tick that playback resumes
+// in does, at the start of the next render. If a seek lands in the middle of a
+// tick, the event's Frame is the start of the tick, before the seek target.
+func (p *Player) SetEventHandler(f func(e *Event)) {
+	p.eventHandler = f
+}
+
+// emitEvent calls the event handler for the tick just processed, which starts
+// at the given frame.
+func (p *Player) emitEvent(frame int64) {
+	if p.eventHandler == nil {
+		return
+	}
+	e := &p.event
+	e.Frame = frame
+	e.Position = p.it.Position()
+	e.Cells = p.it.Cells()
+	e.Speed, e.Tempo = p.speed, p.tempo
+	p.eventHandler(e)
+}
@@ -0,0 +1,121 @@
+package player
+
+import (
+	"testing"
+
+	"github.com/jangler/impulse"
+)
+
+func TestEventHandler(t *testing.T) {
+	m := testModule()
+	rows := m.Patterns[0].Rows
+	rows[0][0] = cell(60, 1, 32, 0, 0)
+	rows[2][3] = cell(62, 1, impulse.VolPanNone, effectS, 0xd2)
+	rows[4][1] = cell(impulse.NoteNone, 0, impulse.VolPanNone, effectZ, 0x80)
+
+	p := New(m, testRate)
+	var events []Event
+	var notes []NoteEvent
+	p.SetEventHandler(func(e *Event) {
+		events = append(events, *e)
+		notes = append(notes, e.Notes...)
+		if e.Position.Row == 4 && e.Cells[1].Command != effectZ {
+			t.Errorf("cells of row 4 do not include cue")
+		}
+	})
+
+	// render in buffers that do not align with ticks
+	buf := make([]float32, 2*100)
+	for p.Render(buf) == len(buf)/2 {
+	}
+
+	// 2.5 / 125 seconds per tick at 44100 Hz is 882 frames
+	if got, want := len(events), 32*6; got != want {
+		t.Fatalf("got %d events; want %d", got, want)
+	}
+	for i, e := range events {
+		if e.Frame != int64(i)*882 || e.Tick != i%6 || e.Position.Row != i/6 ||
+			e.Speed != 6 || e.Tempo != 125 {
+			t.Fatalf("event %d == %+v; want frame %d, row %d, tick %d",
+				i, e, i*882, i/6, i%6)
+		}
+	}
+
+	want := []NoteEvent{
+		{Channel: 0, Note: 60, Instrument: 1, Volume: 32},
+		{Channel: 3, Note: 62, Instrument: 1, Volume: 64},
+	}
+	if len(notes) != len(want) {
+		t.Fatalf("notes == %+v; want %+v", notes, want)
+	}
+	for i := range want {
+		if notes[i] != want[i] {
+			t.Errorf("notes[%d] == %+v; want %+v", i, notes[i], want[i])
+		}
+	}
+	if len(events[2*6].Notes) != 0 || len(events[2*6+2].Notes) != 1 {
+		t.Errorf("delayed note not reported on its tick")
+	}
+
+	// seeking does not produce events, but the tick that playback resumes
+	// in is reported by the next render
+	events = nil
+	p.Seek(0, 2)
+	if len(events) != 0 {
+		t.Errorf("seeking produced %d events", len(events))
+	}
+	p.Render(buf) // does not reach the next tick
+	if len(events) != 1 {
+		t.Fatalf("got %d events after seek; want 1", len(events))
+	}
+	if e := events[0]; e.Position.Row != 2 || e.Tick != 0 ||
+		e.Frame != 2*6*882 || len(e.Notes) != 0 {
+		t.Errorf("event after seek == %+v; want row 2, tick 0", e)
+	}
+
+	// the resumed tick's notes are reported
+	events = nil
+	p.Seek(0, 0)
+	p.Render(buf)
+	if len(events) != 1 || len(events[0].Notes) != 1 {
+		t.Errorf("events after seek to row 0 == %+v; want 1 with a note",
+			events)
+	}
+}
+
+func TestTimingMatchesEvents(t *testing.T) {
+	m := testModule()
+	rows := m.Patterns[0].Rows
+	rows[0][0] = cell(impulse.NoteNone, 0, impulse.VolPanNone, effectS, 0xe2)
+	rows[1][0] = cell(impulse.NoteNone, 0, impulse.VolPanNone, effectS, 0)
+	rows[2][1] = cell(impulse.NoteNone, 0, impulse.VolPanNone, effectT, 128)
+	rows[3][0] = cell(impulse.NoteNone, 0, impulse.VolPanNone, effectA, 3)
+	rows[5][0] = cell(impulse.NoteNone, 0, impulse.VolPanNone, effectS, 0xe2)
+	rows[7][0] = cell(impulse.NoteNone, 0, impulse.VolPanNone, effectT, 0x15)
+
+	var rowFrames []int64
+	p := New(m, testRate)
+	p.SetEventHandler(func(e *Event) {
+		if e.Tick == 0 {
+			rowFrames = append(rowFrames, e.Frame)
+		}
+	})
+	buf := make([]float32, 4096)
+	for p.Render(buf) == len(buf)/2 {
+	}
+
+	// rows 0 and 1 play three times each, from SE2 and S00
+	if got, want := rowFrames[2], int64(6*6*882); got != want {
+		t.Errorf("row 2 starts at frame %d; want %d", got, want)
+	}
+	tm := m.Timing(testRate)
+	if len(tm) != len(rowFrames) {
+		t.Fatalf("len(Timing()) == %d; want %d", len(tm), len(rowFrames))
+	}
+	for i, rt := range tm {
+		if rt.Frame != rowFrames[i] {
+			t.Errorf("Timing()[%d].Frame == %d; want %d", i, rt.Frame,
+				rowFrames[i])
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package player
+
+import "time"
+
+// SetFadeOut sets the length of a fade-out that begins when playback would
+// otherwise stop after the number of loops set by SetLoops. Playback continues
+// through the song's loop during the fade, and stops when it is complete. A
+// duration of zero, the default, stops playback immediately.
+func (p *Player) SetFadeOut(d time.Duration) {
+	p.fadeFrames = int64(d.Seconds() * float64(p.rate))
+}
+
+// Fading returns true if the fade-out set by SetFadeOut is in progress.
+func (p *Player) Fading() bool {
+	return p.fadeLeft > 0
+}
+
+// loopEnd is called when playback reaches the end of its last loop. It
+// returns true if playback should stop.
+func (p *Player) loopEnd() bool {
+	if p.fadeFrames <= 0 {
+		return true
+	}
+	if p.fadeLeft == 0 {
+		p.fadeLeft = p.fadeFrames
+	}
+	return false
+}
+
+// fadeGain returns the gain at the start of the next rendered frame and the
+// change in gain per frame.
+func (p *Player) fadeGain() (gain, delta float32) {
+	if p.fadeLeft == 0 {
+		return 1, 0
+	}
+	return float32(p.fadeLeft) / float32(p.fadeFrames),
+		-1 / float32(p.fadeFrames)
+}
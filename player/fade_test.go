@@ -0,0 +1,50 @@
+package player
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jangler/impulse"
+)
+
+func TestFadeOut(t *testing.T) {
+	m := testModule()
+	m.Patterns[0].Rows[0][0] = cell(60, 1, impulse.VolPanNone, 0, 0)
+	once := len(render(m)) / 2
+
+	p := New(m, testRate)
+	p.SetLoops(1)
+	p.SetFadeOut(time.Second)
+	var out []float32
+	buf := make([]float32, 2*1000)
+	fading := false
+	for {
+		n := p.Render(buf)
+		out = append(out, buf[:n*2]...)
+		fading = fading || p.Fading()
+		if n < len(buf)/2 {
+			break
+		}
+	}
+	if !fading {
+		t.Errorf("Fading() never returned true")
+	}
+	if got, want := len(out)/2, once*2+testRate; got < want-1 || got > want+1 {
+		t.Errorf("rendered %d frames; want %d", got, want)
+	}
+
+	// the fade starts after the last loop and is linear
+	full := peak(out[:once*2])
+	start := out[once*4 : once*4+testRate/10]
+	middle := out[once*4+testRate : once*4+testRate+testRate/10]
+	end := out[len(out)-testRate/10:]
+	if got := peak(start); got < full*0.9 {
+		t.Errorf("peak at start of fade == %v; want about %v", got, full)
+	}
+	if got := peak(middle); got < full*0.4 || got > full*0.6 {
+		t.Errorf("peak in middle of fade == %v; want about %v", got, full/2)
+	}
+	if got := peak(end); got > full*0.1 {
+		t.Errorf("peak at end of fade == %v; want about 0", got)
+	}
+}
@@ -0,0 +1,84 @@
+package player
+
+import "math"
+
+// Filter is Impulse Tracker's two-pole resonant low-pass filter. The zero
+// value passes its input through unchanged until Set is called.
+type Filter struct {
+	a0, b0, b1 float32 // coefficients
+	y1, y2     float32 // previous outputs
+	set        bool
+}
+
+// filterFreq returns the cutoff frequency in Hz for an IT cutoff value in the
+// range 0->127, scaled by a filter envelope value in the range -32->32.
+func filterFreq(cutoff int, env float64, sampleRate int) float64 {
+	c := float64(cutoff) * (env + 32) / 64
+	freq := 110 * math.Exp2(0.25+c/24)
+	if freq < 120 {
+		freq = 120
+	} else if freq > 20000 {
+		freq = 20000
+	}
+	if freq*2 > float64(sampleRate) {
+		freq = float64(sampleRate) / 2
+	}
+	return freq
+}
+
+// Set computes the coefficients of f from IT cutoff and resonance values in
+// the range 0->127 at the given sample rate. env is the value of a filter
+// envelope in the range -32->32; use 32 if there is no filter envelope. The
+// history of f is kept, so that parameters can change while f is in use.
+func (f *Filter) Set(cutoff, resonance int, env float64, sampleRate int) {
+	cutoff, resonance = clamp(cutoff, 0, 127), clamp(resonance, 0, 127)
+	fc := filterFreq(cutoff, env, sampleRate) * 2 * math.Pi /
+		float64(sampleRate)
+	dmpfac := math.Pow(10, -float64(resonance)*24/128/20)
+
+	d := (1 - 2*dmpfac) * fc
+	if d > 2 {
+		d = 2
+	}
+	d = (2*dmpfac - d) / fc
+	e := 1 / (fc * fc)
+
+	f.a0 = float32(1 / (1 + d + e))
+	f.b0 = float32((d + e + e) / (1 + d + e))
+	f.b1 = float32(-e / (1 + d + e))
+	f.set = true
+}
+
+// Reset clears the history of f.
+func (f *Filter) Reset() {
+	f.y1, f.y2 = 0, 0
+}
+
+// Next filters a single sample.
+func (f *Filter) Next(x float32) float32 {
+	if !f.set {
+		return x
+	}
+	y := x*f.a0 + f.y1*f.b0 + f.y2*f.b1
+	// keep high resonance from blowing up
+	if y > 2 {
+		y = 2
+	} else if y < -2 {
+		y = -2
+	}
+	f.y2, f.y1 = f.y1, y
+	return y
+}
+
+// Process filters buf in place.
+func (f *Filter) Process(buf []float32) {
+	for i, x := range buf {
+		buf[i] = f.Next(x)
+	}
+}
+
+// filterEnabled returns true if IT applies the filter for the given cutoff
+// and resonance, which is whenever they do not have their neutral values.
+func filterEnabled(cutoff, resonance int) bool {
+	return cutoff < 127 || resonance > 0
+}
@@ -0,0 +1,82 @@
+package player
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jangler/impulse"
+)
+
+// sine returns n frames of a sine wave with the given frequency.
+func sine(freq float64, n int) []float32 {
+	buf := make([]float32, n)
+	for i := range buf {
+		buf[i] = float32(math.Sin(2 * math.Pi * freq * float64(i) / testRate))
+	}
+	return buf
+}
+
+// filteredPeak returns the peak of a sine wave after filtering, ignoring the
+// filter's initial response.
+func filteredPeak(freq float64, cutoff, resonance int) float32 {
+	var f Filter
+	f.Set(cutoff, resonance, 32, testRate)
+	buf := sine(freq, testRate/4)
+	f.Process(buf)
+	return peak(buf[len(buf)/2:])
+}
+
+func TestFilter(t *testing.T) {
+	var zero Filter
+	if got := zero.Next(0.5); got != 0.5 {
+		t.Errorf("zero Filter.Next(0.5) == %v; want 0.5", got)
+	}
+
+	// cutoff 64 is about 700 Hz
+	if got := filteredPeak(100, 64, 0); math.Abs(float64(got)-1) > 0.05 {
+		t.Errorf("peak below cutoff == %v; want 1", got)
+	}
+	if got := filteredPeak(5000, 64, 0); got > 0.05 {
+		t.Errorf("peak above cutoff == %v; want < 0.05", got)
+	}
+	freq := filterFreq(64, 32, testRate)
+	flat, res := filteredPeak(freq, 64, 0), filteredPeak(freq, 64, 127)
+	if res <= flat*2 {
+		t.Errorf("peak at cutoff with resonance == %v; want > %v", res, flat*2)
+	}
+
+	// a filter envelope at its minimum closes the filter
+	got, want := filterFreq(127, -32, testRate), filterFreq(0, 32, testRate)
+	if got != want {
+		t.Errorf("frequency with minimum envelope == %v; want %v", got, want)
+	}
+	if got := filterFreq(127, 32, 8000); got != 4000 {
+		t.Errorf("frequency at 8000 Hz == %v; want Nyquist", got)
+	}
+}
+
+func TestInstrumentFilter(t *testing.T) {
+	m := testModule()
+	m.Flags |= impulse.UseInstruments
+	ins := &impulse.Instrument{
+		GlobalVolume:     128,
+		DefaultCutoff:    -128 | 16,
+		DefaultResonance: 0,
+		VolumeEnvelope:   &impulse.Envelope{},
+		PanningEnvelope:  &impulse.Envelope{},
+		PitchEnvelope:    &impulse.Envelope{},
+	}
+	for i := range ins.KeyboardTable {
+		ins.KeyboardTable[i] = impulse.NoteSample{Note: uint8(i), Sample: 1}
+	}
+	m.Instruments = []*impulse.Instrument{ins}
+	m.Patterns[0].Rows[0][0] = cell(84, 1, impulse.VolPanNone, 0, 0)
+	filtered := peak(rowSlice(render(m), 1, 4))
+
+	ins.DefaultCutoff = 127
+	unfiltered := peak(rowSlice(render(m), 1, 4))
+	if filtered >= unfiltered/2 {
+		t.Errorf("peak with instrument filter == %v; want < %v",
+			filtered, unfiltered/2)
+	}
+}
@@ -0,0 +1,144 @@
+package player
+
+import "github.com/jangler/impulse"
+
+// MacroParams holds the values substituted for parameter letters when a MIDI
+// macro is encoded.
+type MacroParams struct {
+	Z        uint8  // z: Zxx parameter
+	Channel  uint8  // c: MIDI channel, which is a single hex digit
+	Note     uint8  // n: note
+	Velocity uint8  // v: note velocity
+	Volume   uint8  // u: computed volume
+	Pan      uint8  // x, y: panning
+	Program  uint8  // p: MIDI program
+	Bank     uint16 // a, b: high and low 7 bits of MIDI bank
+}
+
+// hexDigit returns the value of a hexadecimal digit, or -1 if b is not one.
+func hexDigit(b byte) int {
+	switch {
+	case b >= '0' && b <= '9':
+		return int(b - '0')
+	case b >= 'A' && b <= 'F':
+		return int(b-'A') + 10
+	}
+	return -1
+}
+
+// EncodeMacro appends the MIDI bytes of a macro to buf and returns the
+// extended buffer. Pairs of uppercase hexadecimal digits form bytes; c is a
+// single digit, and the other parameter letters (a, b, n, p, u, v, x, y, z)
+// are whole bytes. Spaces and unknown characters are ignored.
+func EncodeMacro(buf []byte, macro string, params MacroParams) []byte {
+	nibble := -1 // pending high nibble, or -1
+	flush := func() {
+		if nibble >= 0 {
+			buf = append(buf, byte(nibble))
+			nibble = -1
+		}
+	}
+	digit := func(d int) {
+		if nibble < 0 {
+			nibble = d
+		} else {
+			buf = append(buf, byte(nibble<<4|d))
+			nibble = -1
+		}
+	}
+	for i := 0; i < len(macro); i++ {
+		if d := hexDigit(macro[i]); d >= 0 {
+			digit(d)
+			continue
+		}
+		var value byte
+		switch macro[i] {
+		case 'c':
+			digit(int(params.Channel & 0xf))
+			continue
+		case 'a':
+			value = byte(params.Bank >> 7 & 0x7f)
+		case 'b':
+			value = byte(params.Bank & 0x7f)
+		case 'n':
+			value = params.Note
+		case 'p':
+			value = params.Program
+		case 'u':
+			value = params.Volume
+		case 'v':
+			value = params.Velocity
+		case 'x', 'y':
+			value = params.Pan
+		case 'z':
+			value = params.Z
+		default:
+			continue
+		}
+		flush()
+		buf = append(buf, value&0x7f)
+	}
+	flush()
+	return buf
+}
+
+// SetMIDIHandler sets a function to be called with MIDI messages sent by Zxx
+// macros that are not interpreted by the player, along with the index of the
+// channel that sent them. msg is only valid for the duration of the call.
+func (p *Player) SetMIDIHandler(f func(channel int, msg []byte)) {
+	p.midiHandler = f
+}
+
+// midiConfig returns the MIDI configuration that applies to the module: the
+// embedded one if present, even if the EmbeddedMIDIConfig flag is not set.
+func midiConfig(m *impulse.Module) *impulse.MIDIConfig {
+	if m.MIDIConfig != nil {
+		return m.MIDIConfig
+	}
+	return impulse.DefaultMIDIConfig()
+}
+
+// macro processes a Zxx effect for c.
+func (c *channel) macro(p *Player, param uint8) {
+	var macro string
+	if param < 0x80 {
+		macro = p.macros.Parametric[c.sfx]
+	} else {
+		macro = p.macros.Fixed[param-0x80]
+	}
+	params := MacroParams{
+		Z:        param,
+		Channel:  uint8(c.index),
+		Note:     c.note,
+		Velocity: uint8(clamp(c.volume*2, 0, 127)),
+		Volume:   uint8(clamp(c.volume*c.chanVolume/32, 0, 127)),
+		Pan:      uint8(clamp(c.pan*2, 0, 127)),
+	}
+	if c.voice != nil && c.voice.ins != nil {
+		ins := c.voice.ins
+		// MIDI channels 1-16 are stored as is; other values, such as 0 for
+		// none, fall back to the tracker channel
+		if ins.MIDIChannel >= 1 && ins.MIDIChannel <= 16 {
+			params.Channel = ins.MIDIChannel - 1
+		}
+		if ins.MIDIProgram >= 0 {
+			params.Program = uint8(ins.MIDIProgram)
+		}
+	}
+	p.macroBuf = EncodeMacro(p.macroBuf[:0], macro, params)
+	msg := p.macroBuf
+
+	// internal filter control is F0 F0 xx yy, where xx is 00 for cutoff or
+	// 01 for resonance
+	for len(msg) >= 4 && msg[0] == 0xf0 && msg[1] == 0xf0 && msg[2] <= 1 {
+		if msg[2] == 0 {
+			c.cutoff = int(msg[3])
+		} else {
+			c.resonance = int(msg[3])
+		}
+		msg = msg[4:]
+	}
+	if len(msg) > 0 && p.midiHandler != nil {
+		p.midiHandler(c.index, msg)
+	}
+}
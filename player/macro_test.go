@@ -0,0 +1,94 @@
+package player
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jangler/impulse"
+)
+
+func TestEncodeMacro(t *testing.T) {
+	params := MacroParams{Z: 0x40, Channel: 0x13, Note: 60, Velocity: 100,
+		Volume: 90, Pan: 64, Program: 5, Bank: 0x181}
+	for _, c := range []struct {
+		macro string
+		want  []byte
+	}{
+		{"F0F000z", []byte{0xf0, 0xf0, 0x00, 0x40}},
+		{"9c n v", []byte{0x93, 60, 100}},
+		{"Cc p", []byte{0xc3, 5}},
+		{"B0 00 a B0 20 b", []byte{0xb0, 0x00, 3, 0xb0, 0x20, 1}},
+		{"u x y", []byte{90, 64, 64}},
+		{"F", []byte{0x0f}},
+		{"Fz", []byte{0x0f, 0x40}},
+		{"", nil},
+	} {
+		if got := EncodeMacro(nil, c.macro, params); !bytes.Equal(got, c.want) {
+			t.Errorf("EncodeMacro(%q) == % x; want % x", c.macro, got, c.want)
+		}
+	}
+}
+
+func TestMacros(t *testing.T) {
+	m := testModule()
+	rows := m.Patterns[0].Rows
+	rows[0][0] = cell(84, 1, impulse.VolPanNone, 0, 0)
+	unfiltered := peak(rowSlice(render(m), 1, 4))
+
+	// SF0 controls cutoff by default
+	rows[0][0] = cell(84, 1, impulse.VolPanNone, effectZ, 0x10)
+	if got := peak(rowSlice(render(m), 1, 4)); got >= unfiltered/2 {
+		t.Errorf("peak with Z10 == %v; want < %v", got, unfiltered/2)
+	}
+
+	// Z8F sets resonance by default, and an embedded configuration can send
+	// MIDI messages, even if the EmbeddedMIDIConfig flag is not set
+	rows[0][0] = cell(84, 1, impulse.VolPanNone, effectZ, 0x8f)
+	rows[1][2] = cell(impulse.NoteNone, 0, impulse.VolPanNone, effectS, 0xf1)
+	rows[2][2] = cell(impulse.NoteNone, 0, impulse.VolPanNone, effectZ, 0x20)
+	m.MIDIConfig = impulse.DefaultMIDIConfig()
+	m.MIDIConfig.Parametric[1] = "F0F000z B0c z"
+	p := New(m, testRate)
+	var msgs [][]byte
+	p.SetMIDIHandler(func(channel int, msg []byte) {
+		if channel != 2 {
+			t.Errorf("MIDI message from channel %d; want 2", channel)
+		}
+		msgs = append(msgs, append([]byte(nil), msg...))
+	})
+	for i := 0; i < 13; i++ {
+		p.processTick()
+	}
+	if got, want := p.channels[0].resonance, 0x78; got != want {
+		t.Errorf("resonance after Z8F == %#x; want %#x", got, want)
+	}
+	if got, want := p.channels[2].cutoff, 0x20; got != want {
+		t.Errorf("cutoff after SF1, Z20 == %#x; want %#x", got, want)
+	}
+	if len(msgs) != 1 || !bytes.Equal(msgs[0], []byte{0xb0, 0x02, 0x20}) {
+		t.Errorf("MIDI messages == % x; want [b0 02 20]", msgs)
+	}
+}
+
+func TestMacroChannel(t *testing.T) {
+	// c is the instrument's MIDI channel, or the tracker channel if the
+	// instrument has none
+	m := stemModule()
+	m.Instruments[0].MIDIChannel = 10
+	m.MIDIConfig = impulse.DefaultMIDIConfig()
+	m.MIDIConfig.Parametric[0] = "Bc z"
+	rows := m.Patterns[0].Rows
+	rows[0][0] = cell(60, 1, 32, effectZ, 0x20)
+	rows[0][1] = cell(48, 2, 32, effectZ, 0x30)
+	p := New(m, testRate)
+	var msgs [][]byte
+	p.SetMIDIHandler(func(channel int, msg []byte) {
+		msgs = append(msgs, append([]byte(nil), msg...))
+	})
+	p.processTick()
+	want := [][]byte{{0xb9, 0x20}, {0xb1, 0x30}}
+	if len(msgs) != len(want) || !bytes.Equal(msgs[0], want[0]) ||
+		!bytes.Equal(msgs[1], want[1]) {
+		t.Errorf("MIDI messages == % x; want % x", msgs, want)
+	}
+}
@@ -0,0 +1,93 @@
+package player
+
+import (
+	"math"
+
+	"github.com/jangler/impulse"
+)
+
+// Clipping determines how Render limits output that exceeds the range -1 to
+// 1.
+type Clipping int
+
+const (
+	// NoClipping leaves the output unchanged.
+	NoClipping Clipping = iota
+
+	// HardClipping clamps each sample to the range -1 to 1.
+	HardClipping
+
+	// SoftClipping leaves samples in the range -0.5 to 0.5 unchanged and
+	// smoothly compresses larger samples into the range -1 to 1.
+	SoftClipping
+
+	// Limiting reduces the gain of both channels as soon as a sample would
+	// exceed the range -1 to 1, and restores it gradually afterwards.
+	Limiting
+)
+
+// soft clipping threshold
+const softKnee = 0.5
+
+// limiter gain recovery time
+const limiterReleaseTime = 0.1 // seconds
+
+// SetClipping sets how Render limits loud output. The default is NoClipping.
+// Clipping is applied to the mixed output, so RenderStems is not affected.
+func (p *Player) SetClipping(c Clipping) {
+	p.clipping = c
+	release := math.Exp(-1 / (limiterReleaseTime * float64(p.rate)))
+	p.limiterRelease = float32(1 - release)
+}
+
+// stereoPan applies the module's stereo separation to a pan value in the range
+// 0->64. In modules without the stereo flag, all voices are centered.
+func (p *Player) stereoPan(pan float64) float64 {
+	if p.mod.Flags&impulse.StereoMixing == 0 {
+		return 32
+	}
+	return 32 + (pan-32)*float64(p.mod.Separation)/128
+}
+
+// softClip compresses a sample smoothly into the range -1 to 1.
+func softClip(s float32) float32 {
+	x := math.Abs(float64(s))
+	if x <= softKnee {
+		return s
+	}
+	y := float32(softKnee + (1-softKnee)*math.Tanh((x-softKnee)/(1-softKnee)))
+	if s < 0 {
+		return -y
+	}
+	return y
+}
+
+// mixdown applies the clipping mode of p to the interleaved stereo samples in
+// buf.
+func (p *Player) mixdown(buf []float32) {
+	switch p.clipping {
+	case HardClipping:
+		for i, s := range buf {
+			if s > 1 {
+				buf[i] = 1
+			} else if s < -1 {
+				buf[i] = -1
+			}
+		}
+	case SoftClipping:
+		for i, s := range buf {
+			buf[i] = softClip(s)
+		}
+	case Limiting:
+		for i := 0; i+1 < len(buf); i += 2 {
+			peak := float32(math.Max(math.Abs(float64(buf[i])),
+				math.Abs(float64(buf[i+1]))))
+			if peak*p.limiterGain > 1 {
+				p.limiterGain = 1 / peak
+			}
+			buf[i] *= p.limiterGain
+			buf[i+1] *= p.limiterGain
+			p.limiterGain += (1 - p.limiterGain) * p.limiterRelease
+		}
+	}
+}
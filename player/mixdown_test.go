@@ -0,0 +1,75 @@
+package player
+
+import (
+	"testing"
+
+	"github.com/jangler/impulse"
+)
+
+func TestStereoMixdown(t *testing.T) {
+	m := testModule()
+	m.Patterns[0].Rows[0][0] = cell(60, 1, 128, 0, 0) // hard left
+	m.Patterns[0].Rows = m.Patterns[0].Rows[:4]
+
+	for _, c := range []struct {
+		name       string
+		separation uint8
+		flags      impulse.ModuleFlag
+		left       bool // true if the output is only on the left
+	}{
+		{"full separation", 128, impulse.StereoMixing, true},
+		{"half separation", 64, impulse.StereoMixing, false},
+		{"no separation", 0, impulse.StereoMixing, false},
+		{"mono", 128, 0, false},
+	} {
+		m.Separation, m.Flags = c.separation, c.flags
+		l, r := stereoPeaks(render(m))
+		switch {
+		case c.left && r != 0:
+			t.Errorf("%s: peaks == %v, %v; want %v, 0", c.name, l, r, l)
+		case !c.left && (r == 0 || r > l):
+			t.Errorf("%s: peaks == %v, %v; want l >= r > 0", c.name, l, r)
+		case c.separation < 128 && c.separation > 0 && l == r:
+			t.Errorf("%s: peaks == %v, %v; want l > r", c.name, l, r)
+		case (c.separation == 0 || c.flags == 0) && l != r:
+			t.Errorf("%s: peaks == %v, %v; want l == r", c.name, l, r)
+		}
+	}
+}
+
+func TestClipping(t *testing.T) {
+	p := New(testModule(), testRate)
+	input := []float32{0.25, -0.25, 1.5, -3, 0.5, 0.25}
+
+	// output is unchanged by default
+	buf := append([]float32(nil), input...)
+	p.mixdown(buf)
+	if !equalOutput(buf, input) {
+		t.Errorf("NoClipping output == %v; want %v", buf, input)
+	}
+
+	p.SetClipping(HardClipping)
+	buf = append(buf[:0], input...)
+	p.mixdown(buf)
+	want := []float32{0.25, -0.25, 1, -1, 0.5, 0.25}
+	if !equalOutput(buf, want) {
+		t.Errorf("HardClipping output == %v; want %v", buf, want)
+	}
+
+	p.SetClipping(SoftClipping)
+	buf = append(buf[:0], input...)
+	p.mixdown(buf)
+	if buf[0] != 0.25 || buf[1] != -0.25 || buf[2] <= 0.5 || buf[2] >= 1 ||
+		buf[3] >= -0.5 || buf[3] <= -1 || buf[2] >= -buf[3] {
+		t.Errorf("SoftClipping output == %v", buf)
+	}
+
+	// the limiter scales both channels by the same gain, then recovers
+	p.SetClipping(Limiting)
+	buf = append(buf[:0], input...)
+	p.mixdown(buf)
+	if buf[0] != 0.25 || buf[1] != -0.25 || buf[3] != -1 || buf[2] != 0.5 ||
+		buf[4] > 0.5/3+0.01 || buf[4] <= 0.5/3 {
+		t.Errorf("Limiting output == %v", buf)
+	}
+}
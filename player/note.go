@@ -0,0 +1,125 @@
+package player
+
+import (
+	"time"
+
+	"github.com/jangler/impulse"
+)
+
+// NoteOptions configures RenderNote.
+type NoteOptions struct {
+	SampleRate int           // output sample rate in Hz; zero means 44100
+	Volume     int           // range 0->64; zero means the sample's default
+	Hold       time.Duration // time before note-off
+	MaxLength  time.Duration // maximum output length; zero means 10 seconds
+}
+
+// noteModule returns a module that plays a single note of ins on its first
+// channel and then idles forever, so that the note can be released and left
+// to ring out.
+func noteModule(ins *impulse.Instrument, samples []*impulse.Sample, note uint8,
+	volume int) *impulse.Module {
+	m := &impulse.Module{
+		GlobalVolume:   128,
+		MixingVolume:   128,
+		Separation:     128,
+		InitialSpeed:   1,
+		InitialTempo:   125,
+		ChannelPanning: make([]uint8, 64),
+		ChannelVolume:  make([]uint8, 64),
+		OrderList:      []uint8{0, 1, impulse.OrderEnd},
+		Samples:        samples,
+		Instruments:    []*impulse.Instrument{ins},
+		Flags: impulse.StereoMixing | impulse.UseInstruments |
+			impulse.LinearSlides,
+		Patterns: []*impulse.Pattern{
+			impulse.NewPattern(1), impulse.NewPattern(1),
+		},
+	}
+	for i := range m.ChannelPanning {
+		m.ChannelPanning[i] = 32
+		m.ChannelVolume[i] = 64
+	}
+	c := &m.Patterns[0].Rows[0][0]
+	c.Note, c.Instrument = note, 1
+	if volume > 0 {
+		c.VolPan = uint8(clamp(volume, 0, 64))
+	}
+	// jump back to the empty pattern instead of retriggering the note
+	c = &m.Patterns[1].Rows[0][0]
+	c.Command, c.Parameter = effectB, 1
+	return m
+}
+
+// RenderNote renders a single note played with an instrument and returns it
+// as interleaved stereo samples. samples holds the instrument's samples,
+// numbered from 1 as in its keyboard table, which is the order they are
+// stored in ITI files.
+//
+// The note is played as if in a module: the keyboard table, envelopes, swing,
+// filter defaults, and auto-vibrato of the instrument and its samples apply.
+// After opts.Hold, the note is released, and rendering continues until the
+// note has stopped or opts.MaxLength is reached.
+func RenderNote(ins *impulse.Instrument, samples []*impulse.Sample, note uint8,
+	opts NoteOptions) []float32 {
+	if opts.SampleRate <= 0 {
+		opts.SampleRate = defaultSampleRate
+	}
+	if opts.MaxLength <= 0 {
+		opts.MaxLength = 10 * time.Second
+	}
+	if ins == nil || note >= 120 {
+		return nil
+	}
+	p := New(noteModule(ins, samples, note, opts.Volume), opts.SampleRate)
+	p.SetLoops(-1)
+
+	toFrames := func(d time.Duration) int {
+		return int(d * time.Duration(opts.SampleRate) / time.Second)
+	}
+	maxFrames, holdFrames := toFrames(opts.MaxLength), toFrames(opts.Hold)
+	// the note starts on the first frame, so it can't be released before then
+	holdFrames = clamp(holdFrames, 1, maxFrames)
+	var out []float32
+	buf := make([]float32, 2048)
+	released := false
+	for len(out) < 2*maxFrames {
+		n := len(buf) / 2
+		if !released && holdFrames-len(out)/2 < n {
+			n = holdFrames - len(out)/2
+		}
+		if left := maxFrames - len(out)/2; left < n {
+			n = left
+		}
+		if n > 0 {
+			n = p.Render(buf[:n*2])
+			out = append(out, buf[:n*2]...)
+		}
+		if !released && len(out)/2 >= holdFrames {
+			if v := p.channels[0].voice; v != nil {
+				v.noteOff()
+			}
+			released = true
+		}
+		if released && !p.playing() {
+			break
+		}
+	}
+	return out
+}
+
+// playing returns true if any voice of p is active, including voices that
+// are ramping down after being cut.
+func (p *Player) playing() bool {
+	for i := range p.voices {
+		if p.voices[i].active {
+			return true
+		}
+	}
+	for i := range p.declick {
+		if p.declick[i].active {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package player
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jangler/impulse"
+)
+
+func TestRenderNote(t *testing.T) {
+	ins := &impulse.Instrument{
+		GlobalVolume:    128,
+		FadeOut:         256,
+		VolumeEnvelope:  &impulse.Envelope{},
+		PanningEnvelope: &impulse.Envelope{},
+		PitchEnvelope:   &impulse.Envelope{},
+	}
+	for i := range ins.KeyboardTable {
+		ins.KeyboardTable[i] = impulse.NoteSample{Note: uint8(i), Sample: 1}
+	}
+	samples := []*impulse.Sample{squareSample()}
+	opts := NoteOptions{SampleRate: testRate, Hold: 100 * time.Millisecond}
+
+	// the looped sample rings until the fadeout ends, 1024/256 ticks after
+	// note-off
+	out := RenderNote(ins, samples, 60, opts)
+	hold := testRate / 10
+	if frames := len(out) / 2; frames <= hold || frames > hold+rowFrames {
+		t.Errorf("rendered %d frames; want %d to %d", frames, hold,
+			hold+rowFrames)
+	}
+	if peak(out[:hold*2]) == 0 {
+		t.Errorf("held note is silent")
+	}
+
+	// volume
+	opts.Volume = 16
+	if quiet := RenderNote(ins, samples, 60, opts); peak(quiet) >= peak(out) {
+		t.Errorf("peak with volume 16 == %v; want < %v", peak(quiet), peak(out))
+	}
+	opts.Volume = 0
+
+	// unmapped note
+	ins.KeyboardTable[61].Sample = 0
+	if out := RenderNote(ins, samples, 61, opts); peak(out) != 0 {
+		t.Errorf("unmapped note is not silent")
+	}
+
+	// notes that never end are cut off at the maximum length
+	ins.FadeOut = 0
+	opts.MaxLength = time.Second
+	if out := RenderNote(ins, samples, 60, opts); len(out) != testRate*2 {
+		t.Errorf("rendered %d frames; want %d", len(out)/2, testRate)
+	}
+}
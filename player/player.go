@@ -0,0 +1,325 @@
+// Package player renders Impulse Tracker modules to PCM audio.
+package player
+
+import (
+	"math/rand"
+
+	"github.com/jangler/impulse"
+)
+
+// Player renders an impulse.Module to interleaved stereo PCM audio.
+type Player struct {
+	mod     *impulse.Module
+	rate    int
+	samples [][]float32 // decoded sample data, indexed like mod.Samples
+	rng     *rand.Rand
+	interp  Interpolation
+	quirks  *quirks
+	it      *impulse.RowIterator
+	loops   int // number of times to play the song's loop, or -1
+	done    bool
+	frame   int64 // number of frames rendered since the start of the song
+	newRow  bool  // true if the last tick processed was the start of a row
+
+	rampFrames int   // length of volume ramps
+	fadeFrames int64 // length of the fade-out after the last loop
+	fadeLeft   int64 // frames left in the fade-out, if fading
+
+	macros      *impulse.MIDIConfig
+	macroBuf    []byte
+	midiHandler func(channel int, msg []byte)
+
+	event        Event
+	eventHandler func(e *Event)
+	pendingEvent int64 // start of a tick processed by seeking, or -1
+
+	controls controls
+	scopes   *scopes // recorded signals, or nil
+
+	clipping       Clipping
+	limiterGain    float32
+	limiterRelease float32 // limiter gain recovery per frame
+
+	speed, tempo int
+	globalVolume int // range 0->128
+
+	tick       int // tick number within the current row
+	rowDelay   int // number of times the current row is repeated (SEx)
+	tickDelay  int // extra ticks added to the current row (S6x)
+	tickFrames int // frames left in the current tick
+	frameFrac  float64
+
+	channels [64]channel
+	voices   [maxVoices]voice
+	declick  [declickVoices]voice // cut voices that are ramping down
+}
+
+// sample rate used in place of a rate that is not positive
+const defaultSampleRate = 44100
+
+// New returns a Player that renders m at the given sample rate in Hz, or at
+// 44100 Hz if sampleRate is not positive. By default, playback stops when the
+// song would loop; see SetLoops.
+func New(m *impulse.Module, sampleRate int) *Player {
+	if sampleRate <= 0 {
+		sampleRate = defaultSampleRate
+	}
+	p := &Player{
+		mod:      m,
+		rate:     sampleRate,
+		samples:  make([][]float32, len(m.Samples)),
+		interp:   Linear,
+		quirks:   &profileQuirks[DetectProfile(m)],
+		macros:   midiConfig(m),
+		macroBuf: make([]byte, 0, 32),
+		event:    Event{Notes: make([]NoteEvent, 0, 64)},
+	}
+	for i, s := range m.Samples {
+		p.samples[i] = decodeSample(s)
+	}
+	p.SetVolumeRamp(DefaultVolumeRamp)
+	p.SetClipping(NoClipping)
+	p.controls.reset()
+	p.reset()
+	return p
+}
+
+// reset returns p to the start of the song.
+func (p *Player) reset() {
+	m := p.mod
+	p.rng = rand.New(rand.NewSource(1))
+	p.it = m.Iterate(-1)
+	p.it.LoopResetsStart = p.quirks.loopResetsStart
+	p.done = false
+	p.frame = 0
+	p.fadeLeft = 0
+	p.pendingEvent = -1
+	p.limiterGain = 1
+	p.speed, p.tempo = int(m.InitialSpeed), int(m.InitialTempo)
+	if p.speed == 0 {
+		p.speed = 6
+	}
+	if p.tempo < 32 {
+		p.tempo = 125
+	}
+	p.globalVolume = clamp(int(m.GlobalVolume), 0, 128)
+	p.tick, p.rowDelay, p.tickDelay = 0, 0, 0
+	p.tickFrames, p.frameFrac = 0, 0
+
+	for i := range p.channels {
+		c := &p.channels[i]
+		*c = channel{index: i, chanVolume: 64, pan: 32, cutoff: 127}
+		if i < len(m.ChannelVolume) {
+			c.chanVolume = clamp(int(m.ChannelVolume[i]), 0, 64)
+		}
+		if i < len(m.ChannelPanning) {
+			c.pan = int(m.ChannelPanning[i] & 0x7f)
+			c.muted = m.ChannelPanning[i]&0x80 != 0
+			if c.pan > 64 && c.pan != panSurround {
+				c.pan = 32
+			}
+		}
+		c.nnaOverride, c.noteDelay, c.noteCut = -1, -1, -1
+	}
+	p.stopVoices()
+}
+
+// SetLoops sets the number of times that playback repeats the looping part of
+// the song before stopping. If n is negative, playback never stops.
+func (p *Player) SetLoops(n int) {
+	p.loops = n
+}
+
+// SetInterpolation sets the interpolation used to resample notes. The default
+// is Linear.
+func (p *Player) SetInterpolation(i Interpolation) {
+	p.interp = i
+}
+
+// stopVoices immediately stops all playing voices.
+func (p *Player) stopVoices() {
+	for i := range p.voices {
+		p.voices[i].active = false
+	}
+	for i := range p.declick {
+		p.declick[i].active = false
+	}
+	p.restartScopes()
+}
+
+// Position returns the position of the row currently being played.
+func (p *Player) Position() impulse.Position {
+	return p.it.Position()
+}
+
+// rowTicks returns the total number of ticks in the current row.
+func (p *Player) rowTicks() int {
+	return p.speed*(p.rowDelay+1) + p.tickDelay
+}
+
+// startRow advances to the next row and processes its first tick. It returns
+// false if the song is over.
+func (p *Player) startRow() bool {
+	if !p.it.Next() {
+		return false
+	}
+	if p.it.Looped() && p.loops >= 0 && p.it.Loops() > p.loops &&
+		p.fadeLeft == 0 && p.loopEnd() {
+		return false
+	}
+	cells := p.it.Cells()
+	p.rowDelay, p.tickDelay = -1, 0
+	for i := range p.channels {
+		cell := cells[i]
+		if cell.Command == effectS {
+			param := cell.Parameter
+			if param == 0 {
+				param = p.channels[i].sMem
+			}
+			switch param >> 4 {
+			case 0x6:
+				p.tickDelay += int(param & 0xf)
+			case 0xe:
+				if p.rowDelay < 0 {
+					p.rowDelay = int(param & 0xf)
+				}
+			}
+		}
+		p.channels[i].processRow(p, cell)
+	}
+	if p.rowDelay < 0 {
+		p.rowDelay = 0
+	}
+	return true
+}
+
+// processTick advances playback by one tick.
+func (p *Player) processTick() {
+	p.newRow = p.tick == 0
+	p.event.Tick = p.tick
+	p.event.Notes = p.event.Notes[:0]
+	if p.tick == 0 {
+		if !p.startRow() {
+			p.done = true
+			return
+		}
+	} else {
+		// row repetitions from SEx restart the effect tick counter, and S6x
+		// ticks continue it from the last repetition
+		tick := p.tick % p.speed
+		if p.tick >= p.speed*(p.rowDelay+1) {
+			tick = p.tick - p.speed*p.rowDelay
+		}
+		for i := range p.channels {
+			p.channels[i].processTick(p, tick, p.tick)
+		}
+	}
+
+	for i := range p.channels {
+		p.channels[i].updateVoice()
+	}
+	for i := range p.voices {
+		if p.voices[i].active {
+			p.voices[i].update(p)
+		}
+	}
+
+	p.tick++
+	if p.tick >= p.rowTicks() {
+		p.tick = 0
+	}
+
+	// IT's tick length is 2.5 / tempo seconds
+	frames := float64(p.rate)*2.5/float64(p.tempo) + p.frameFrac
+	p.tickFrames = int(frames)
+	p.frameFrac = frames - float64(p.tickFrames)
+}
+
+// Render fills buf with interleaved stereo samples and returns the number of
+// frames rendered. The returned count is less than len(buf)/2 only if the end
+// of the song was reached. The output is limited as set by SetClipping.
+//
+// Render does not allocate memory or take locks, so it may be called from a
+// realtime audio callback. Handlers set by SetEventHandler and SetMIDIHandler
+// are called synchronously from Render, and should also avoid blocking.
+func (p *Player) Render(buf []float32) int {
+	for i := range buf {
+		buf[i] = 0
+	}
+	n := p.render(len(buf)/2, func(*voice) []float32 { return buf })
+	p.mixdown(buf[:n*2])
+	return n
+}
+
+// render advances playback by up to the given number of frames, mixing each
+// voice into the buffer returned by dest, and returns the number of frames
+// rendered. If dest returns nil, the voice is not mixed.
+func (p *Player) render(frames int, dest func(*voice) []float32) int {
+	if p.pendingEvent >= 0 && frames > 0 {
+		if !p.done {
+			p.emitEvent(p.pendingEvent)
+		}
+		p.pendingEvent = -1
+	}
+	pos := 0
+	for pos < frames {
+		if p.tickFrames == 0 {
+			if p.done {
+				break
+			}
+			p.processTick()
+			if p.done {
+				break
+			}
+			p.emitEvent(p.frame + int64(pos))
+		}
+		n := frames - pos
+		if n > p.tickFrames {
+			n = p.tickFrames
+		}
+		if p.fadeLeft > 0 && int64(n) > p.fadeLeft {
+			n = int(p.fadeLeft)
+		}
+		gain, delta := p.fadeGain()
+		if p.scopes != nil {
+			p.clearScopes(p.frame+int64(pos), n)
+		}
+		for i := range p.voices {
+			p.mixVoice(&p.voices[i], p.voiceScope(i), dest, pos, n, gain,
+				delta)
+		}
+		for i := range p.declick {
+			p.mixVoice(&p.declick[i], nil, dest, pos, n, gain, delta)
+		}
+		p.tickFrames -= n
+		pos += n
+		if p.fadeLeft > 0 {
+			if p.fadeLeft -= int64(n); p.fadeLeft == 0 {
+				p.done = true
+				p.tickFrames = 0
+			}
+		}
+	}
+	p.frame += int64(pos)
+	if p.scopes != nil {
+		p.publishScopes()
+	}
+	return pos
+}
+
+// mixVoice mixes n frames of v into the buffer returned by dest, starting at
+// frame pos, if v is active. If scopes are enabled, the output is also
+// recorded, using vs as the voice's scope.
+func (p *Player) mixVoice(v *voice, vs scope, dest func(*voice) []float32,
+	pos, n int, gain, delta float32) {
+	if !v.active {
+		return
+	}
+	if p.scopes != nil {
+		p.mixScoped(v, vs, dest(v), pos, n, gain, delta)
+	} else if buf := dest(v); buf != nil {
+		v.mix(buf[pos*2:(pos+n)*2], n, gain, delta)
+	} else {
+		v.skip(n)
+	}
+}
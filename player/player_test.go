@@ -0,0 +1,383 @@
+package player
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jangler/impulse"
+)
+
+const testRate = 44100
+
+// squareSample returns a looped 8-bit square wave sample with a period of 64
+// frames.
+func squareSample() *impulse.Sample {
+	s := &impulse.Sample{
+		GlobalVolume:  64,
+		Flags:         impulse.SampleAssociatedWithHeader | impulse.Loop,
+		DefaultVolume: 64,
+		Signed:        true,
+		Length:        64,
+		LoopEnd:       64,
+		Speed:         8363,
+		Data:          make([]byte, 64),
+	}
+	for i := range s.Data {
+		if i < 32 {
+			s.Data[i] = 0x7f
+		} else {
+			s.Data[i] = 0x80
+		}
+	}
+	return s
+}
+
+// testModule returns a module containing a square wave sample and a single
+// 32-row pattern, to be filled in by the caller.
+func testModule() *impulse.Module {
+	m := &impulse.Module{
+		GlobalVolume:   128,
+		MixingVolume:   128,
+		Separation:     128,
+		InitialSpeed:   6,
+		InitialTempo:   125,
+		Flags:          impulse.StereoMixing | impulse.LinearSlides,
+		ChannelPanning: make([]uint8, 64),
+		ChannelVolume:  make([]uint8, 64),
+		OrderList:      []uint8{0, impulse.OrderEnd},
+		Samples:        []*impulse.Sample{squareSample()},
+		Patterns:       []*impulse.Pattern{impulse.NewPattern(32)},
+	}
+	for i := range m.ChannelPanning {
+		m.ChannelPanning[i] = 32
+		m.ChannelVolume[i] = 64
+	}
+	return m
+}
+
+func cell(note, ins, volpan, cmd, param uint8) impulse.Cell {
+	return impulse.Cell{Note: note, Instrument: ins, VolPan: volpan,
+		Command: cmd, Parameter: param}
+}
+
+// render renders all of m and returns the output.
+func render(m *impulse.Module) []float32 {
+	p := New(m, testRate)
+	var out []float32
+	buf := make([]float32, 4096)
+	for {
+		n := p.Render(buf)
+		out = append(out, buf[:n*2]...)
+		if n < len(buf)/2 {
+			return out
+		}
+	}
+}
+
+// peak returns the maximum absolute sample value in buf.
+func peak(buf []float32) float32 {
+	var max float32
+	for _, v := range buf {
+		if v < 0 {
+			v = -v
+		}
+		if v > max {
+			max = v
+		}
+	}
+	return max
+}
+
+// stereoPeaks returns the peaks of the left and right channels of buf.
+func stereoPeaks(buf []float32) (float32, float32) {
+	left := make([]float32, len(buf)/2)
+	right := make([]float32, len(buf)/2)
+	for i := range left {
+		left[i], right[i] = buf[i*2], buf[i*2+1]
+	}
+	return peak(left), peak(right)
+}
+
+// rowFrames is the number of frames in a row at speed 6, tempo 125.
+const rowFrames = testRate * 6 / 50
+
+// rowSlice returns the part of buf containing rows [first, last).
+func rowSlice(buf []float32, first, last int) []float32 {
+	return buf[first*rowFrames*2 : last*rowFrames*2]
+}
+
+func TestRenderLength(t *testing.T) {
+	m := testModule()
+	m.Patterns[0].Rows[4][0] = cell(impulse.NoteNone, 0, impulse.VolPanNone,
+		effectT, 150)
+	out := render(m)
+	want := int(m.Duration().Total.Seconds() * testRate)
+	if got := len(out) / 2; got < want-1 || got > want+1 {
+		t.Errorf("rendered %d frames; want %d", got, want)
+	}
+	if got := peak(out); got != 0 {
+		t.Errorf("peak of empty song == %v; want 0", got)
+	}
+
+	// a sample rate that is not positive is replaced by the default
+	p := New(m, 0)
+	if n := p.Render(make([]float32, 2*1024)); n != 1024 {
+		t.Errorf("rendered %d frames at rate 0; want 1024", n)
+	}
+	if got, want := p.rate, 44100; got != want {
+		t.Errorf("rate of player created with rate 0 == %v; want %v", got,
+			want)
+	}
+}
+
+func TestRenderNotes(t *testing.T) {
+	m := testModule()
+	rows := m.Patterns[0].Rows
+	rows[0][0] = cell(60, 1, impulse.VolPanNone, 0, 0)
+	rows[4][0] = cell(impulse.NoteNone, 0, 32, 0, 0)
+	rows[8][0] = cell(impulse.NoteCut, 0, impulse.VolPanNone, 0, 0)
+	rows[12][0] = cell(60, 1, impulse.VolPanNone, effectM, 16)
+	rows[16][0] = cell(impulse.NoteOff, 0, impulse.VolPanNone, 0, 0)
+	p := New(m, testRate)
+	p.SetVolumeRamp(0)
+	out := renderPlayer(p)
+
+	// centered pan splits full volume between both channels
+	full := peak(rowSlice(out, 0, 4))
+	if math.Abs(float64(full)-0.5) > 0.01 {
+		t.Errorf("peak at full volume == %v; want 0.5", full)
+	}
+	if got := peak(rowSlice(out, 4, 8)); math.Abs(float64(got-full/2)) > 0.01 {
+		t.Errorf("peak at half volume == %v; want %v", got, full/2)
+	}
+	if got := peak(rowSlice(out, 8, 12)); got != 0 {
+		t.Errorf("peak after note cut == %v; want 0", got)
+	}
+	if got := peak(rowSlice(out, 12, 16)); math.Abs(float64(got-full/4)) >
+		0.01 {
+		t.Errorf("peak at quarter channel volume == %v; want %v", got, full/4)
+	}
+
+	// note off in sample mode only releases the sustain loop
+	if got := peak(rowSlice(out, 16, 20)); got == 0 {
+		t.Errorf("peak after note off == 0; want nonzero")
+	}
+}
+
+func TestRenderPanning(t *testing.T) {
+	m := testModule()
+	rows := m.Patterns[0].Rows
+	rows[0][0] = cell(60, 1, 128, 0, 0)
+	rows[4][0] = cell(impulse.NoteNone, 0, 192, 0, 0)
+	out := render(m)
+	if l, r := stereoPeaks(rowSlice(out, 1, 4)); l == 0 || r != 0 {
+		t.Errorf("peaks with left pan == %v, %v; want nonzero, 0", l, r)
+	}
+	if l, r := stereoPeaks(rowSlice(out, 5, 8)); l != 0 || r == 0 {
+		t.Errorf("peaks with right pan == %v, %v; want 0, nonzero", l, r)
+	}
+}
+
+func TestEffects(t *testing.T) {
+	tests := []struct {
+		cell  impulse.Cell
+		ticks int
+		check func(p *Player, c *channel) bool
+		desc  string
+	}{
+		{
+			cell(60, 1, impulse.VolPanNone, effectF, 0x02), 6,
+			func(p *Player, c *channel) bool {
+				return math.Abs(c.freq-8363*math.Exp2(40.0/768)) < 0.01
+			},
+			"F02 slides pitch up by 5*8 units",
+		},
+		{
+			cell(60, 1, impulse.VolPanNone, effectE, 0xf4), 6,
+			func(p *Player, c *channel) bool {
+				return math.Abs(c.freq-8363*math.Exp2(-16.0/768)) < 0.01
+			},
+			"EF4 slides pitch down by 16 units",
+		},
+		{
+			cell(60, 1, impulse.VolPanNone, effectD, 0x04), 6,
+			func(p *Player, c *channel) bool { return c.volume == 44 },
+			"D04 slides volume down by 5*4",
+		},
+		{
+			cell(60, 1, 32, effectD, 0x3f), 6,
+			func(p *Player, c *channel) bool { return c.volume == 35 },
+			"D3F fine slides volume up by 3",
+		},
+		{
+			cell(60, 1, impulse.VolPanNone, effectA, 0x03), 1,
+			func(p *Player, c *channel) bool { return p.speed == 3 },
+			"A03 sets speed",
+		},
+		{
+			cell(60, 1, impulse.VolPanNone, effectT, 0x12), 6,
+			func(p *Player, c *channel) bool { return p.tempo == 135 },
+			"T12 slides tempo up by 5*2",
+		},
+		{
+			cell(60, 1, impulse.VolPanNone, effectV, 0x40), 1,
+			func(p *Player, c *channel) bool { return p.globalVolume == 64 },
+			"V40 sets global volume",
+		},
+		{
+			cell(60, 1, impulse.VolPanNone, effectX, 0x00), 1,
+			func(p *Player, c *channel) bool { return c.pan == 0 },
+			"X00 sets pan",
+		},
+		{
+			cell(60, 1, impulse.VolPanNone, effectS, 0xc2), 3,
+			func(p *Player, c *channel) bool { return c.volume == 0 },
+			"SC2 cuts note",
+		},
+		{
+			cell(60, 1, impulse.VolPanNone, effectS, 0xd3), 3,
+			func(p *Player, c *channel) bool { return c.voice == nil },
+			"SD3 delays note",
+		},
+		{
+			cell(60, 1, impulse.VolPanNone, effectS, 0xd3), 4,
+			func(p *Player, c *channel) bool { return c.voice != nil },
+			"SD3 delays note",
+		},
+		{
+			cell(60, 1, impulse.VolPanNone, effectJ, 0x47), 2,
+			func(p *Player, c *channel) bool { return c.pitchDelta == 4*64 },
+			"J47 arpeggiates",
+		},
+		{
+			cell(60, 1, impulse.VolPanNone, effectO, 0x10), 1,
+			func(p *Player, c *channel) bool {
+				return c.voice != nil && c.voice.pos == 0
+			},
+			"O10 past end of sample is ignored",
+		},
+	}
+
+	for _, test := range tests {
+		m := testModule()
+		m.Patterns[0].Rows[0][0] = test.cell
+		p := New(m, testRate)
+		for i := 0; i < test.ticks; i++ {
+			p.processTick()
+		}
+		if !test.check(p, &p.channels[0]) {
+			t.Errorf("%s: check failed after %d ticks", test.desc, test.ticks)
+		}
+	}
+}
+
+func TestNoteDelayMemory(t *testing.T) {
+	// S00 repeats the SD3 of the previous row
+	m := testModule()
+	rows := m.Patterns[0].Rows
+	rows[0][0] = cell(impulse.NoteNone, 0, impulse.VolPanNone, effectS, 0xd3)
+	rows[1][0] = cell(60, 1, impulse.VolPanNone, effectS, 0)
+	p := New(m, testRate)
+	for i := 0; i < 6+3; i++ {
+		p.processTick()
+	}
+	if p.channels[0].voice != nil {
+		t.Errorf("S00 after SD3 did not delay note")
+	}
+	p.processTick()
+	if p.channels[0].voice == nil {
+		t.Errorf("note delayed by S00 after SD3 did not start on tick 3")
+	}
+}
+
+func TestPortamento(t *testing.T) {
+	m := testModule()
+	rows := m.Patterns[0].Rows
+	rows[0][0] = cell(60, 1, impulse.VolPanNone, 0, 0)
+	rows[1][0] = cell(72, 0, impulse.VolPanNone, effectG, 0x08)
+	for i := 2; i < 6; i++ {
+		rows[i][0] = cell(impulse.NoteNone, 0, impulse.VolPanNone, effectG, 0)
+	}
+	p := New(m, testRate)
+	for i := 0; i < 12; i++ {
+		p.processTick()
+	}
+	c := &p.channels[0]
+	want := 8363 * math.Exp2(5*32.0/768)
+	if math.Abs(c.freq-want) > 0.01 {
+		t.Errorf("frequency after G08 == %v; want %v", c.freq, want)
+	}
+	if c.voice != &p.voices[0] {
+		t.Errorf("G08 retriggered note")
+	}
+
+	// portamento stops at the target note
+	for i := 0; i < 24; i++ {
+		p.processTick()
+	}
+	if want := 8363.0 * 2; math.Abs(c.freq-want) > 0.01 {
+		t.Errorf("frequency after G00 == %v; want %v", c.freq, want)
+	}
+}
+
+func TestInstrument(t *testing.T) {
+	m := testModule()
+	m.Flags |= impulse.UseInstruments
+	ins := &impulse.Instrument{
+		NewNoteAction: impulse.NewNoteContinue,
+		FadeOut:       256,
+		GlobalVolume:  128,
+		VolumeEnvelope: &impulse.Envelope{
+			Flags: impulse.EnvelopeOn | impulse.EnvelopeSusLoopOn,
+			NodePoints: []impulse.NodePoint{
+				{Value: 64, Tick: 0}, {Value: 32, Tick: 2},
+			},
+			SusLoopBegin: 1,
+			SusLoopEnd:   1,
+		},
+		PanningEnvelope: &impulse.Envelope{},
+		PitchEnvelope:   &impulse.Envelope{},
+	}
+	for i := range ins.KeyboardTable {
+		ins.KeyboardTable[i] = impulse.NoteSample{Note: uint8(i), Sample: 1}
+	}
+	m.Instruments = []*impulse.Instrument{ins}
+	rows := m.Patterns[0].Rows
+	rows[0][0] = cell(60, 1, impulse.VolPanNone, 0, 0)
+	rows[2][0] = cell(67, 1, impulse.VolPanNone, 0, 0)
+	rows[4][0] = cell(impulse.NoteOff, 0, impulse.VolPanNone, 0, 0)
+
+	p := New(m, testRate)
+	for i := 0; i < 18; i++ {
+		p.processTick()
+	}
+
+	// NNA continue leaves the first note playing in the background
+	var active []*voice
+	for i := range p.voices {
+		if p.voices[i].active {
+			active = append(active, &p.voices[i])
+		}
+	}
+	if len(active) != 2 || active[0].foreground || !active[1].foreground {
+		t.Fatalf("voices after second note: %d active; want 1 background, "+
+			"1 foreground", len(active))
+	}
+	if got, want := active[1].volEnv.value, 32.0; got != want {
+		t.Errorf("sustained envelope value == %v; want %v", got, want)
+	}
+
+	// note off ends the envelope, then fadeout takes four ticks
+	for i := 0; i < 8; i++ {
+		p.processTick()
+	}
+	if !active[1].active || !active[1].fading {
+		t.Errorf("voice not fading after note off")
+	}
+	for i := 0; i < 2; i++ {
+		p.processTick()
+	}
+	if active[1].active {
+		t.Errorf("voice still active after note off and fadeout")
+	}
+}
@@ -0,0 +1,74 @@
+package player
+
+import "github.com/jangler/impulse"
+
+// Profile selects how a Player handles edge cases in which trackers differ.
+type Profile int
+
+const (
+	ImpulseTracker Profile = iota // Impulse Tracker 2.14
+	SchismTracker
+	OpenMPT
+)
+
+// quirks are the behaviors that differ between profiles.
+type quirks struct {
+	// Gxx with an instrument number switches to the new instrument's sample
+	portaSwitchesSample bool
+
+	// Gxx with an instrument number restarts the envelopes and fadeout of
+	// the playing note
+	portaResetsEnvelopes bool
+
+	// a new note resets the Qxy retrigger counter
+	retrigResetsOnNote bool
+
+	// a finished SBx loop moves the channel's loop start to the next row
+	loopResetsStart bool
+
+	// a new note of the same instrument continues envelopes that have the
+	// carry flag from the channel's previous note
+	envelopeCarry bool
+}
+
+var profileQuirks = [...]quirks{
+	ImpulseTracker: {
+		portaResetsEnvelopes: true,
+		loopResetsStart:      true,
+	},
+	SchismTracker: {
+		portaResetsEnvelopes: true,
+		retrigResetsOnNote:   true,
+		loopResetsStart:      true,
+		envelopeCarry:        true,
+	},
+	OpenMPT: {
+		portaSwitchesSample: true,
+		retrigResetsOnNote:  true,
+		envelopeCarry:       true,
+	},
+}
+
+// DetectProfile returns the profile matching the tracker that created m, as
+// indicated by m.CreatedWith. Modules from unknown trackers use the
+// ImpulseTracker profile.
+func DetectProfile(m *impulse.Module) Profile {
+	switch {
+	case m.CreatedWith>>12 == 0x1:
+		return SchismTracker
+	case m.CreatedWith>>12 == 0x5, m.CreatedWith == 0x0888:
+		// 0x0888 is written by OpenMPT's predecessor, ModPlug Tracker
+		return OpenMPT
+	}
+	return ImpulseTracker
+}
+
+// SetProfile sets the playback profile of p. By default, the profile is
+// chosen by DetectProfile.
+func (p *Player) SetProfile(profile Profile) {
+	if profile < 0 || int(profile) >= len(profileQuirks) {
+		profile = ImpulseTracker
+	}
+	p.quirks = &profileQuirks[profile]
+	p.it.LoopResetsStart = p.quirks.loopResetsStart
+}
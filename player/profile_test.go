@@ -0,0 +1,89 @@
+package player
+
+import (
+	"testing"
+
+	"github.com/jangler/impulse"
+)
+
+func TestDetectProfile(t *testing.T) {
+	for _, c := range []struct {
+		cwtv uint16
+		want Profile
+	}{
+		{0x0214, ImpulseTracker},
+		{0x0000, ImpulseTracker},
+		{0x1822, SchismTracker},
+		{0x5119, OpenMPT},
+		{0x0888, OpenMPT},
+	} {
+		m := &impulse.Module{CreatedWith: c.cwtv}
+		if got := DetectProfile(m); got != c.want {
+			t.Errorf("DetectProfile(%#x) == %v; want %v", c.cwtv, got, c.want)
+		}
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	m := testModule()
+	m.Samples = append(m.Samples, squareSample())
+	rows := m.Patterns[0].Rows
+	rows[0][0] = cell(60, 1, impulse.VolPanNone, effectQ, 0x04)
+	rows[1][0] = cell(62, 1, impulse.VolPanNone, effectQ, 0x04)
+	rows[2][1] = cell(60, 1, impulse.VolPanNone, 0, 0)
+	rows[3][1] = cell(67, 2, impulse.VolPanNone, effectG, 0x10)
+
+	// envelope carry, from a note held for one row
+	im := stemModule()
+	im.Instruments[0].VolumeEnvelope = &impulse.Envelope{
+		Flags: impulse.EnvelopeOn | impulse.EnvelopeCarry,
+		NodePoints: []impulse.NodePoint{
+			{Value: 64, Tick: 0}, {Value: 0, Tick: 100},
+		},
+	}
+	im.Patterns[0].Rows[1][0] = cell(62, 1, impulse.VolPanNone, 0, 0)
+
+	for _, c := range []struct {
+		profile     Profile
+		retrigCount int // retrigger count on the first tick of the second note
+		sample      int // sample playing on channel 1 after Gxx
+		loopReset   bool
+		envTick     int // envelope tick after the first tick of the second note
+	}{
+		{ImpulseTracker, 3, 0, true, 1},
+		{SchismTracker, 1, 0, true, 7},
+		{OpenMPT, 1, 1, false, 7},
+	} {
+		p := New(m, testRate)
+		p.SetProfile(c.profile)
+		if got := p.it.LoopResetsStart; got != c.loopReset {
+			t.Errorf("profile %d: LoopResetsStart == %v; want %v",
+				c.profile, got, c.loopReset)
+		}
+		for i := 0; i < 7; i++ {
+			p.processTick()
+		}
+		if got := p.channels[0].retrigCount; got != c.retrigCount {
+			t.Errorf("profile %d: retrigger count after new note == %d; "+
+				"want %d", c.profile, got, c.retrigCount)
+		}
+		for i := 0; i < 12; i++ {
+			p.processTick()
+		}
+		v := p.channels[1].voice
+		if v == nil || v.sample != m.Samples[c.sample] {
+			t.Errorf("profile %d: sample after Gxx is not sample %d",
+				c.profile, c.sample+1)
+		}
+
+		p = New(im, testRate)
+		p.SetProfile(c.profile)
+		for i := 0; i < 7; i++ {
+			p.processTick()
+		}
+		if got := p.channels[0].voice.volEnv.it.Tick(); got != c.envTick {
+			t.Errorf("profile %d: envelope tick of second note == %d; want %d",
+				c.profile, got, c.envTick)
+		}
+	}
+}
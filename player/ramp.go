@@ -0,0 +1,68 @@
+package player
+
+import "time"
+
+// DefaultVolumeRamp is the default length of volume ramps; see SetVolumeRamp.
+const DefaultVolumeRamp = 2 * time.Millisecond
+
+// number of voices that can ramp down at once after being cut
+const declickVoices = 32
+
+// SetVolumeRamp sets the time over which changes in a voice's volume are
+// spread to avoid clicks. Notes ramp up when they start and ramp down when
+// they are cut, including voices cut or stolen by new notes, which crossfade
+// with the notes that replace them. Volume changes from effects and envelopes
+// ramp from one tick to the next. A duration of zero disables ramping, so that
+// volume changes are sample-accurate as in Impulse Tracker. The default is
+// DefaultVolumeRamp.
+func (p *Player) SetVolumeRamp(d time.Duration) {
+	p.rampFrames = int(d.Seconds() * float64(p.rate))
+}
+
+// cutVoice stops v immediately. If volume ramping is enabled, a copy of v
+// keeps playing in the background while its volume ramps down.
+func (p *Player) cutVoice(v *voice) {
+	if p.rampFrames > 0 && v.active && v.curL+v.curR != 0 {
+		for i := range p.declick {
+			d := &p.declick[i]
+			if !d.active {
+				*d = *v
+				d.foreground, d.stopping = false, true
+				d.lvol, d.rvol = 0, 0
+				d.setRamp(p.rampFrames)
+				break
+			}
+		}
+	}
+	v.active = false
+}
+
+// setRamp starts a ramp from the current volume of v to its mixing volume
+// over the given number of frames.
+func (v *voice) setRamp(frames int) {
+	if frames <= 0 {
+		v.curL, v.curR, v.rampLeft = v.lvol, v.rvol, 0
+		return
+	}
+	v.rampL = (v.lvol - v.curL) / float32(frames)
+	v.rampR = (v.rvol - v.curR) / float32(frames)
+	v.rampLeft = frames
+}
+
+// stepRamp advances the volume ramp of v by n frames. A stopping voice is
+// deactivated at the end of its ramp.
+func (v *voice) stepRamp(n int) {
+	if v.rampLeft == 0 {
+		return
+	}
+	if n < v.rampLeft {
+		v.curL += v.rampL * float32(n)
+		v.curR += v.rampR * float32(n)
+		v.rampLeft -= n
+		return
+	}
+	v.curL, v.curR, v.rampLeft = v.lvol, v.rvol, 0
+	if v.stopping {
+		v.active = false
+	}
+}
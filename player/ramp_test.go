@@ -0,0 +1,61 @@
+package player
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jangler/impulse"
+)
+
+func TestVolumeRamp(t *testing.T) {
+	m := testModule()
+	rows := m.Patterns[0].Rows
+	rows[0][0] = cell(60, 1, impulse.VolPanNone, 0, 0)
+	rows[1][0] = cell(impulse.NoteCut, 0, impulse.VolPanNone, 0, 0)
+	rows[2][0] = cell(60, 1, impulse.VolPanNone, 0, 0)
+	rows[3][0] = cell(60, 1, impulse.VolPanNone, 0, 0) // NNA cut
+	m.Patterns[0].Rows = rows[:4]
+
+	p := New(m, testRate)
+	p.SetVolumeRamp(time.Millisecond)
+	ramp := testRate / 1000
+	out := renderPlayer(p)
+	if got := peak(out[:4]); got > 0.5/float32(ramp)+1e-6 {
+		t.Errorf("peak of first frames == %v; want ramp from 0", got)
+	}
+	full := peak(out)
+	if got := peak(out[2*ramp : 2*rowFrames]); got != full {
+		t.Errorf("peak after ramp == %v; want %v", got, full)
+	}
+	if got := peak(rowSlice(out, 1, 2)[:2*ramp]); got == 0 {
+		t.Errorf("cut note stops without ramping down")
+	}
+	if got := peak(rowSlice(out, 1, 2)[2*ramp:]); got != 0 {
+		t.Errorf("peak after ramping down cut note == %v; want 0", got)
+	}
+
+	// the cut voice crossfades with the new note
+	p = New(m, testRate)
+	p.SetVolumeRamp(time.Millisecond)
+	p.Render(make([]float32, 2*3*rowFrames))
+	buf := make([]float32, 2*ramp/2)
+	p.Render(buf)
+	if activeVoices(p) != 1 || !p.declick[0].active {
+		t.Errorf("cut voice is not ramping down after new note")
+	}
+	if got := peak(buf); got < full*0.9 {
+		t.Errorf("peak during crossfade == %v; want about %v", got, full)
+	}
+
+	// no ramping
+	p = New(m, testRate)
+	p.SetVolumeRamp(0)
+	out = renderPlayer(p)
+	if got := peak(out[:2]); got < full*0.9 {
+		t.Errorf("peak of first frame without ramping == %v; want about %v",
+			got, full)
+	}
+	if got := peak(rowSlice(out, 1, 2)); got != 0 {
+		t.Errorf("peak after cut without ramping == %v; want 0", got)
+	}
+}
@@ -0,0 +1,110 @@
+package player
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// bytes per frame of 16-bit stereo PCM
+const frameBytes = 4
+
+// Reader reads the output of a Player as 16-bit signed little-endian
+// interleaved stereo PCM. Seek offsets are in bytes of PCM data from the start
+// of the song, and are rounded down to a whole frame.
+type Reader struct {
+	p       *Player
+	buf     []float32
+	frame   [frameBytes]byte
+	pending []byte // unread bytes of a partially read frame
+}
+
+// NewReader returns a Reader that reads from p.
+func NewReader(p *Player) *Reader {
+	return &Reader{p: p, buf: make([]float32, 4096)}
+}
+
+// putFrame converts a stereo frame to PCM bytes.
+func putFrame(b []byte, l, r float32) {
+	for i, v := range [2]float32{l, r} {
+		if v > 1 {
+			v = 1
+		} else if v < -1 {
+			v = -1
+		}
+		s := int16(v * 32767)
+		b[i*2], b[i*2+1] = byte(s), byte(s>>8)
+	}
+}
+
+// Read reads PCM data into b. It returns io.EOF once playback has ended.
+func (r *Reader) Read(b []byte) (int, error) {
+	n := copy(b, r.pending)
+	r.pending = r.pending[n:]
+	for n < len(b) {
+		frames := (len(b) - n) / frameBytes
+		if frames == 0 {
+			frames = 1
+		} else if frames > len(r.buf)/2 {
+			frames = len(r.buf) / 2
+		}
+		got := r.p.Render(r.buf[:frames*2])
+		if got == 0 {
+			break
+		}
+		for i := 0; i < got; i++ {
+			if len(b)-n >= frameBytes {
+				putFrame(b[n:], r.buf[i*2], r.buf[i*2+1])
+				n += frameBytes
+			} else {
+				putFrame(r.frame[:], r.buf[i*2], r.buf[i*2+1])
+				m := copy(b[n:], r.frame[:])
+				r.pending = r.frame[m:]
+				n += m
+			}
+		}
+	}
+	if n == 0 && len(b) > 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+// Seek implements io.Seeker. io.SeekEnd is not supported, since the length of
+// the song is not known in advance.
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.p.frame*frameBytes - int64(len(r.pending))
+	default:
+		return 0, errors.New("player: unsupported seek whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("player: negative seek offset")
+	}
+	r.pending = nil
+	if err := r.p.seekFrame(offset / frameBytes); err != nil {
+		return 0, err
+	}
+	return r.p.frame * frameBytes, nil
+}
+
+// SeekPosition moves playback to the given order and row, as with
+// Player.Seek.
+func (r *Reader) SeekPosition(order, row int) error {
+	if err := r.p.Seek(order, row); err != nil {
+		return err
+	}
+	r.pending = nil
+	return nil
+}
+
+// SeekTime moves playback to the given time offset, as with Player.SeekTime.
+func (r *Reader) SeekTime(t time.Duration) error {
+	if err := r.p.SeekTime(t); err != nil {
+		return err
+	}
+	r.pending = nil
+	return nil
+}
@@ -0,0 +1,64 @@
+package player
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func TestReader(t *testing.T) {
+	m := stateModule()
+	want := render(m)
+
+	// read everything using an odd buffer size
+	r := NewReader(New(m, testRate))
+	var out bytes.Buffer
+	buf := make([]byte, 1023)
+	for {
+		n, err := r.Read(buf)
+		out.Write(buf[:n])
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Reader.Read() returned error: %v", err)
+		}
+	}
+	data := out.Bytes()
+	if got, want := len(data), len(want)*2; got != want {
+		t.Fatalf("read %d bytes; want %d", got, want)
+	}
+	for i := 0; i < len(want); i++ {
+		got := int16(binary.LittleEndian.Uint16(data[i*2:]))
+		if w := int16(want[i] * 32767); got != w {
+			t.Fatalf("sample %d == %d; want %d", i, got, w)
+		}
+	}
+
+	// seek and read the rest
+	offset, err := r.Seek(int64(len(data)/2+1), io.SeekStart)
+	if err != nil {
+		t.Fatalf("Reader.Seek() returned error: %v", err)
+	}
+	if got, want := offset, int64(len(data)/2+1)/4*4; got != want {
+		t.Errorf("Reader.Seek() == %v; want %v", got, want)
+	}
+	if cur, _ := r.Seek(0, io.SeekCurrent); cur != offset {
+		t.Errorf("Reader.Seek(0, io.SeekCurrent) == %v; want %v", cur, offset)
+	}
+	rest, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ioutil.ReadAll() returned error: %v", err)
+	}
+	if got, want := len(rest), len(data)-int(offset); got != want {
+		t.Errorf("read %d bytes after seek; want %d", got, want)
+	}
+
+	if _, err := r.Seek(0, io.SeekEnd); err == nil {
+		t.Errorf("Reader.Seek(0, io.SeekEnd) did not return error")
+	}
+	if err := r.SeekPosition(0, 4); err != nil {
+		t.Errorf("Reader.SeekPosition(0, 4) returned error: %v", err)
+	}
+}
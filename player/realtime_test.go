@@ -0,0 +1,96 @@
+package player
+
+import (
+	"testing"
+
+	"github.com/jangler/impulse"
+)
+
+// busyModule returns a looping module that exercises instruments, envelopes,
+// new note actions, filters, macros, and effects on many channels.
+func busyModule() *impulse.Module {
+	m := stemModule()
+	ins := m.Instruments[0]
+	ins.FadeOut = 8
+	ins.DefaultCutoff = -128 | 90
+	ins.DefaultResonance = -128 | 40
+	ins.VolumeSwing = 20
+	ins.PanSwing = 8
+	ins.VolumeEnvelope = &impulse.Envelope{
+		Flags: impulse.EnvelopeOn | impulse.EnvelopeSusLoopOn,
+		NodePoints: []impulse.NodePoint{
+			{Value: 64, Tick: 0}, {Value: 32, Tick: 8}, {Value: 0, Tick: 40},
+		},
+		SusLoopBegin: 1,
+		SusLoopEnd:   1,
+	}
+	ins.PitchEnvelope = &impulse.Envelope{
+		Flags: impulse.EnvelopeOn | impulse.EnvelopeUseFilter,
+		NodePoints: []impulse.NodePoint{
+			{Value: 32, Tick: 0}, {Value: -32, Tick: 20},
+		},
+	}
+	m.Samples[0].VibratoDepth = 16
+	m.Samples[0].VibratoSpeed = 8
+
+	effects := []struct{ cmd, param uint8 }{
+		{effectH, 0x44}, {effectQ, 0x13}, {effectZ, 0x40}, {effectD, 0x01},
+		{effectE, 0x02}, {effectR, 0x33}, {effectY, 0x24}, {effectJ, 0x37},
+	}
+	rows := m.Patterns[0].Rows
+	for r := range rows {
+		for ch := 0; ch < 16; ch++ {
+			rows[r][ch] = impulse.EmptyCell
+			if (r+ch)%3 == 0 {
+				e := effects[(r*ch)%len(effects)]
+				rows[r][ch] = cell(uint8(36+(r*7+ch*5)%48), uint8(1+ch%2),
+					impulse.VolPanNone, e.cmd, e.param)
+			}
+		}
+	}
+	return m
+}
+
+func TestRenderAllocs(t *testing.T) {
+	p := New(busyModule(), testRate)
+	p.SetLoops(-1)
+	p.SetEventHandler(func(*Event) {})
+	p.SetMIDIHandler(func(int, []byte) {})
+	buf := make([]float32, 2*512)
+	stems := make([][]float32, 16)
+	for i := range stems {
+		stems[i] = make([]float32, 2*512)
+	}
+
+	// warm up, so that reusable buffers reach their final size
+	for i := 0; i < 100; i++ {
+		p.Render(buf)
+	}
+	if n := testing.AllocsPerRun(200, func() { p.Render(buf) }); n != 0 {
+		t.Errorf("Render allocated %v times per call; want 0", n)
+	}
+	if n := testing.AllocsPerRun(200, func() {
+		p.RenderStems(stems, StemsByChannel)
+	}); n != 0 {
+		t.Errorf("RenderStems allocated %v times per call; want 0", n)
+	}
+	p.SetScopeLength(1024, true)
+	if n := testing.AllocsPerRun(200, func() { p.Render(buf) }); n != 0 {
+		t.Errorf("Render with scopes allocated %v times per call; want 0", n)
+	}
+}
+
+func BenchmarkRender(b *testing.B) {
+	p := New(busyModule(), testRate)
+	p.SetLoops(-1)
+	buf := make([]float32, 2*512)
+	p.Render(buf)
+	if n := testing.AllocsPerRun(10, func() { p.Render(buf) }); n != 0 {
+		b.Fatalf("Render allocated %v times per call; want 0", n)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Render(buf)
+	}
+}
@@ -0,0 +1,282 @@
+package player
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/jangler/impulse"
+)
+
+// decodeSample converts the PCM data of s to float32 values in the range
+// -1->1. Stereo samples are decoded as their left channel only. Compressed
+// samples and samples without data are returned as nil.
+func decodeSample(s *impulse.Sample) []float32 {
+	if s == nil || s.Flags&impulse.SampleAssociatedWithHeader == 0 ||
+		s.Flags&impulse.Compressed != 0 {
+		return nil
+	}
+
+	if s.Flags&impulse.Quality16Bit != 0 {
+		n := int(s.Length)
+		if len(s.Data)/2 < n {
+			n = len(s.Data) / 2
+		}
+		data := make([]float32, n)
+		for i := range data {
+			v := binary.LittleEndian.Uint16(s.Data[i*2:])
+			if s.Signed {
+				data[i] = float32(int16(v)) / 32768
+			} else {
+				data[i] = (float32(v) - 32768) / 32768
+			}
+		}
+		return data
+	}
+
+	n := int(s.Length)
+	if len(s.Data) < n {
+		n = len(s.Data)
+	}
+	data := make([]float32, n)
+	for i := range data {
+		if s.Signed {
+			data[i] = float32(int8(s.Data[i])) / 128
+		} else {
+			data[i] = (float32(s.Data[i]) - 128) / 128
+		}
+	}
+	return data
+}
+
+// Interpolation is a method of resampling sample data.
+type Interpolation int
+
+const (
+	Nearest Interpolation = iota // no interpolation
+	Linear                       // two-point linear interpolation
+	Cubic                        // four-point Catmull-Rom spline
+	Sinc                         // eight-point windowed sinc
+)
+
+// Voice plays a single sample at an arbitrary pitch, honoring its loops. The
+// sustain loop is active until Release is called. Output is mono, and does not
+// include the sample's default or global volume.
+type Voice struct {
+	Interpolation Interpolation
+
+	sample *impulse.Sample
+	data   []float32
+	rate   int
+
+	active  bool
+	keyOn   bool
+	pos     float64 // position in sample frames
+	reverse bool    // playing backwards in a ping-pong loop
+	step    float64 // sample frames per output frame
+}
+
+// NewVoice returns a Voice that plays s at the given sample rate in Hz, or at
+// 44100 Hz if sampleRate is not positive, using linear interpolation. The
+// voice plays at the sample's C-5 speed until its pitch is changed. If s has
+// no playable data, the voice is silent.
+func NewVoice(s *impulse.Sample, sampleRate int) *Voice {
+	if sampleRate <= 0 {
+		sampleRate = defaultSampleRate
+	}
+	v := &Voice{
+		Interpolation: Linear,
+		sample:        s,
+		data:          decodeSample(s),
+		rate:          sampleRate,
+	}
+	v.Restart()
+	if s != nil {
+		v.SetFrequency(float64(s.Speed))
+	}
+	return v
+}
+
+// Restart returns v to the start of the sample and presses the key again.
+func (v *Voice) Restart() {
+	v.active = len(v.data) > 0
+	v.keyOn = true
+	v.pos = 0
+	v.reverse = false
+}
+
+// SetNote sets the pitch of v to a note in the range 0->119 (C-0 -> B-9).
+func (v *Voice) SetNote(note uint8) {
+	if v.sample == nil {
+		return
+	}
+	v.SetFrequency(v.sample.NoteFrequency(note))
+}
+
+// SetFrequency sets the playback rate of v to the given frequency in Hz.
+func (v *Voice) SetFrequency(freq float64) {
+	v.step = freq / float64(v.rate)
+}
+
+// SetPosition moves v to the given sample frame.
+func (v *Voice) SetPosition(frame int) {
+	v.pos = float64(frame)
+	v.reverse = false
+	v.active = frame >= 0 && frame < len(v.data)
+}
+
+// Position returns the current position of v in sample frames.
+func (v *Voice) Position() float64 {
+	return v.pos
+}
+
+// Release releases the key, exiting the sustain loop.
+func (v *Voice) Release() {
+	v.keyOn = false
+}
+
+// Playing returns true if v has not reached the end of its sample.
+func (v *Voice) Playing() bool {
+	return v.active
+}
+
+// Read fills buf with mono samples and returns the number of frames read. The
+// returned count is less than len(buf) only if the end of the sample was
+// reached.
+func (v *Voice) Read(buf []float32) int {
+	for i := range buf {
+		if !v.active {
+			return i
+		}
+		buf[i] = v.value()
+		v.advance(1)
+	}
+	return len(buf)
+}
+
+// loop returns the active loop bounds of v, and whether the loop is ping-pong.
+// If there is no active loop, end is zero.
+func (v *Voice) loop() (begin, end int, pingPong bool) {
+	s := v.sample
+	length := uint32(len(v.data))
+	if v.keyOn && s.Flags&impulse.SustainLoop != 0 &&
+		s.SustainLoopBegin < s.SustainLoopEnd && s.SustainLoopEnd <= length {
+		return int(s.SustainLoopBegin), int(s.SustainLoopEnd),
+			s.Flags&impulse.PingPongSustainLoop != 0
+	}
+	if s.Flags&impulse.Loop != 0 && s.LoopBegin < s.LoopEnd &&
+		s.LoopEnd <= length {
+		return int(s.LoopBegin), int(s.LoopEnd),
+			s.Flags&impulse.PingPongLoop != 0
+	}
+	return 0, 0, false
+}
+
+// foldPingPong converts a distance travelled from the start of a ping-pong
+// loop into an offset from its start, and returns true if playback is moving
+// backwards at that offset. span is the distance between the first and last
+// frames of the loop, which are each played once per cycle.
+func foldPingPong(x, span float64) (float64, bool) {
+	if span <= 0 {
+		return 0, false
+	}
+	x = math.Mod(x, 2*span)
+	if x > span {
+		return 2*span - x, true
+	}
+	return x, false
+}
+
+// at returns sample frame i as heard in playback, following the active loop
+// past its end, or the loop start while playing backwards in a ping-pong loop.
+func (v *Voice) at(i, begin, end int, pingPong bool) float32 {
+	if end != 0 {
+		switch {
+		case pingPong && i >= end:
+			x, _ := foldPingPong(float64(i-begin), float64(end-1-begin))
+			i = begin + int(x)
+		case pingPong && v.reverse && i < begin:
+			x, _ := foldPingPong(float64(begin-i), float64(end-1-begin))
+			i = begin + int(x)
+		case i >= end:
+			i = begin + (i-end)%(end-begin)
+		}
+	}
+	if i < 0 {
+		return 0
+	}
+	if i >= len(v.data) {
+		return v.data[len(v.data)-1]
+	}
+	return v.data[i]
+}
+
+// value returns the interpolated sample value at the current position of v.
+func (v *Voice) value() float32 {
+	idx := int(v.pos)
+	if idx >= len(v.data) || idx < 0 {
+		v.active = false
+		return 0
+	}
+	begin, end, pingPong := v.loop()
+	frac64 := v.pos - float64(idx)
+	frac := float32(frac64)
+	switch v.Interpolation {
+	case Nearest:
+		return v.data[idx]
+	case Cubic:
+		p0, p1 := v.at(idx-1, begin, end, pingPong), v.data[idx]
+		p2 := v.at(idx+1, begin, end, pingPong)
+		p3 := v.at(idx+2, begin, end, pingPong)
+		a := -0.5*p0 + 1.5*p1 - 1.5*p2 + 0.5*p3
+		b := p0 - 2.5*p1 + 2*p2 - 0.5*p3
+		c := -0.5*p0 + 0.5*p2
+		return ((a*frac+b)*frac+c)*frac + p1
+	case Sinc:
+		// frac64 is less than 1, but may round up to 1 as a float32
+		phase := int(frac64 * sincPhases)
+		if phase >= sincPhases {
+			phase = sincPhases - 1
+		}
+		weights := &sincTable[phase]
+		var sum float32
+		for i, w := range weights {
+			sum += w * v.at(idx-sincTaps/2+1+i, begin, end, pingPong)
+		}
+		return sum
+	}
+	next := v.at(idx+1, begin, end, pingPong)
+	return v.data[idx] + (next-v.data[idx])*frac
+}
+
+// advance moves the position of v forward by n frames.
+func (v *Voice) advance(n int) {
+	begin, end, pingPong := v.loop()
+	if !pingPong {
+		// a released sustain loop continues forwards from where it was
+		v.reverse = false
+	}
+	span := float64(end - 1 - begin)
+	for i := 0; i < n; i++ {
+		if v.reverse {
+			v.pos -= v.step
+			if v.pos < float64(begin) {
+				x, reverse := foldPingPong(float64(begin)-v.pos, span)
+				v.pos, v.reverse = float64(begin)+x, reverse
+			}
+		} else {
+			v.pos += v.step
+			switch {
+			case end == 0:
+			case pingPong && v.pos >= float64(end-1):
+				x, reverse := foldPingPong(v.pos-float64(begin), span)
+				v.pos, v.reverse = float64(begin)+x, reverse
+			case !pingPong && v.pos >= float64(end):
+				v.pos = float64(begin) +
+					math.Mod(v.pos-float64(begin), float64(end-begin))
+			}
+		}
+	}
+	if v.pos >= float64(len(v.data)) {
+		v.active = false
+	}
+}
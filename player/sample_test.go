@@ -0,0 +1,152 @@
+package player
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jangler/impulse"
+)
+
+// rampSample returns an unlooped 8-bit sample whose values rise by 1/128 per
+// frame.
+func rampSample() *impulse.Sample {
+	s := &impulse.Sample{
+		GlobalVolume:  64,
+		Flags:         impulse.SampleAssociatedWithHeader,
+		DefaultVolume: 64,
+		Signed:        true,
+		Length:        64,
+		Speed:         testRate,
+		Data:          make([]byte, 64),
+	}
+	for i := range s.Data {
+		s.Data[i] = byte(i)
+	}
+	return s
+}
+
+func TestVoiceInterpolation(t *testing.T) {
+	for _, c := range []struct {
+		interp Interpolation
+		want   func(i int) float64
+	}{
+		{Nearest, func(i int) float64 { return float64(i/2) / 128 }},
+		{Linear, func(i int) float64 { return float64(i) / 256 }},
+		{Cubic, func(i int) float64 { return float64(i) / 256 }},
+		{Sinc, func(i int) float64 { return float64(i) / 256 }},
+	} {
+		v := NewVoice(rampSample(), testRate)
+		v.Interpolation = c.interp
+		v.SetNote(48)
+		buf := make([]float32, 128)
+		if n := v.Read(buf); n != len(buf) {
+			t.Fatalf("interpolation %d: read %d frames; want %d",
+				c.interp, n, len(buf))
+		}
+		// skip the edges, where neighboring frames are outside the sample
+		for i := 8; i < 112; i++ {
+			if math.Abs(float64(buf[i])-c.want(i)) > 0.002 {
+				t.Errorf("interpolation %d: frame %d == %v; want %v",
+					c.interp, i, buf[i], c.want(i))
+				break
+			}
+		}
+	}
+}
+
+func TestVoiceLoops(t *testing.T) {
+	buf := make([]float32, 1000)
+
+	// no loop
+	v := NewVoice(rampSample(), testRate)
+	if n := v.Read(buf); n != 64 || v.Playing() {
+		t.Errorf("unlooped sample: read %d frames; want 64", n)
+	}
+
+	// sustain loop until release, then play to the end
+	s := rampSample()
+	s.Flags |= impulse.SustainLoop
+	s.SustainLoopBegin, s.SustainLoopEnd = 16, 32
+	v = NewVoice(s, testRate)
+	if n := v.Read(buf); n != len(buf) || v.Position() >= 32 {
+		t.Errorf("sustain loop: read %d frames, at %v; want %d, < 32",
+			n, v.Position(), len(buf))
+	}
+	v.Release()
+	if n := v.Read(buf); n >= 64 {
+		t.Errorf("released sustain loop: read %d frames; want < 64", n)
+	}
+
+	// ping-pong loop
+	s = rampSample()
+	s.Flags |= impulse.Loop | impulse.PingPongLoop
+	s.LoopBegin, s.LoopEnd = 0, 64
+	v = NewVoice(s, testRate)
+	v.Interpolation = Nearest
+	v.Read(buf[:74])
+	if got, want := v.Position(), 52.0; got != want {
+		t.Errorf("ping-pong position after 74 frames == %v; want %v", got, want)
+	}
+	v.Read(buf[:2])
+	if got, want := buf[0], float32(52)/128; got != want {
+		t.Errorf("ping-pong value == %v; want %v", got, want)
+	}
+	if got, want := buf[1], float32(51)/128; got != want {
+		t.Errorf("ping-pong value == %v; want %v", got, want)
+	}
+
+	// both ends of a ping-pong loop are played once per cycle
+	s.LoopBegin, s.LoopEnd = 4, 8
+	v = NewVoice(s, testRate)
+	v.Interpolation = Nearest
+	v.Read(buf[:16])
+	frames := []int{0, 1, 2, 3, 4, 5, 6, 7, 6, 5, 4, 5, 6, 7, 6, 5}
+	for i, f := range frames {
+		if buf[i] != float32(f)/128 {
+			t.Errorf("ping-pong frames == %v; want %v", buf[:16], frames)
+			break
+		}
+	}
+
+	// a ping-pong sustain loop released while playing backwards continues
+	// forwards
+	s = rampSample()
+	s.Flags |= impulse.SustainLoop | impulse.PingPongSustainLoop
+	s.SustainLoopBegin, s.SustainLoopEnd = 4, 8
+	v = NewVoice(s, testRate)
+	v.Interpolation = Nearest
+	v.Read(buf[:10])
+	v.Release()
+	if n := v.Read(buf); n != 60 || buf[0] != 4.0/128 || buf[1] != 5.0/128 {
+		t.Errorf("released ping-pong sustain loop: read %d frames "+
+			"starting with %v; want 60 starting with [4 5]/128", n, buf[:2])
+	}
+
+	// restart
+	v.Restart()
+	if !v.Playing() || v.Position() != 0 {
+		t.Errorf("voice not at start after Restart")
+	}
+	if v := NewVoice(nil, testRate); v.Playing() || v.Read(buf) != 0 {
+		t.Errorf("voice without sample is playing")
+	}
+
+	// a sample rate that is not positive is replaced by the default
+	v = NewVoice(rampSample(), -1)
+	v.Read(buf[:4])
+	if got, want := v.Position(), 4.0*testRate/44100; got != want {
+		t.Errorf("position at rate -1 == %v; want %v", got, want)
+	}
+}
+
+func TestVoiceSincLongLoop(t *testing.T) {
+	// positions just below an integer must not select a phase past the end
+	// of the sinc table
+	v := NewVoice(squareSample(), testRate)
+	v.Interpolation = Sinc
+	v.SetNote(60)
+	buf := make([]float32, 10*testRate)
+	if n := v.Read(buf); n != len(buf) {
+		t.Errorf("read %d frames; want %d", n, len(buf))
+	}
+}
@@ -0,0 +1,317 @@
+package player
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// number of frames mixed at once when recording scopes
+const scopeChunk = 1024
+
+// scopeFresh is set in scopes.state when a snapshot has been published since
+// the last call to Player.Scopes.
+const scopeFresh = 1 << 31
+
+// Level holds the peak and RMS levels of the left and right sides of a stereo
+// signal.
+type Level struct {
+	Peak, RMS [2]float32
+}
+
+// levelOf returns the levels of interleaved stereo samples.
+func levelOf(buf []float32) Level {
+	var l Level
+	var sum [2]float64
+	for i, x := range buf {
+		if x < 0 {
+			x = -x
+		}
+		if x > l.Peak[i%2] {
+			l.Peak[i%2] = x
+		}
+		sum[i%2] += float64(x) * float64(x)
+	}
+	if n := len(buf) / 2; n > 0 {
+		for k := range sum {
+			l.RMS[k] = float32(math.Sqrt(sum[k] / float64(n)))
+		}
+	}
+	return l
+}
+
+// VoiceStatus describes the state of one of a Player's voices.
+type VoiceStatus struct {
+	Active     bool
+	Channel    int        // index of the host channel
+	Foreground bool       // false if the host channel moved on to a new note
+	Instrument int        // instrument number, or sample number in sample mode
+	Sample     int        // sample number
+	Note       int        // note after keyboard table mapping
+	Position   float64    // playback position in sample frames
+	Volume     [2]float32 // left and right mixing volume
+}
+
+// status returns the state of v.
+func (v *voice) status() VoiceStatus {
+	return VoiceStatus{
+		Active:     v.active,
+		Channel:    v.channel,
+		Foreground: v.foreground,
+		Instrument: int(v.insNum),
+		Sample:     int(v.smpNum),
+		Note:       int(v.note),
+		Position:   v.pos,
+		Volume:     [2]float32{v.curL, v.curR},
+	}
+}
+
+// Scopes is a snapshot of the signals recorded by a Player for oscilloscopes
+// and level meters, taken at the end of a call to Render or RenderStems.
+// Signals are interleaved stereo samples, oldest first, and end at Frame.
+type Scopes struct {
+	Frame int64 // offset in frames from the start of the song
+
+	// Channels holds the recent output of each channel, including background
+	// voices started by the channel.
+	Channels [64][]float32
+
+	// Voices holds the state of each voice. VoiceScopes holds the output of
+	// each voice since it started playing its current note, or is nil if
+	// voice scopes are disabled.
+	Voices      []VoiceStatus
+	VoiceScopes [][]float32
+}
+
+// ChannelLevel returns the levels of a channel's recorded output.
+func (s *Scopes) ChannelLevel(ch int) Level {
+	if ch < 0 || ch >= len(s.Channels) {
+		return Level{}
+	}
+	return levelOf(s.Channels[ch])
+}
+
+// VoiceLevel returns the levels of a voice's recorded output.
+func (s *Scopes) VoiceLevel(i int) Level {
+	if i < 0 || i >= len(s.VoiceScopes) {
+		return Level{}
+	}
+	return levelOf(s.VoiceScopes[i])
+}
+
+// scope is a ring buffer holding the most recent frames of a signal as
+// interleaved stereo samples, indexed by frame offset from the start of the
+// song.
+type scope []float32
+
+// index returns the index in s of the left sample of the given frame.
+func (s scope) index(frame int64) int {
+	return int(frame%int64(len(s)/2)) * 2
+}
+
+// add adds interleaved stereo samples to s, starting at the given frame.
+func (s scope) add(frame int64, samples []float32) {
+	// only the end of the samples fits in the buffer
+	if skip := len(samples) - len(s); skip > 0 {
+		frame += int64(skip / 2)
+		samples = samples[skip:]
+	}
+	for i := 0; i < len(samples); i += 2 {
+		j := s.index(frame + int64(i/2))
+		s[j] += samples[i]
+		s[j+1] += samples[i+1]
+	}
+}
+
+// write replaces the n frames of s starting at the given frame with samples,
+// or with zeros if samples is nil.
+func (s scope) write(frame int64, n int, samples []float32) {
+	if skip := n - len(s)/2; skip > 0 {
+		frame += int64(skip)
+		n -= skip
+		if samples != nil {
+			samples = samples[skip*2:]
+		}
+	}
+	for i := 0; i < n; i++ {
+		j := s.index(frame + int64(i))
+		if samples == nil {
+			s[j], s[j+1] = 0, 0
+		} else {
+			s[j], s[j+1] = samples[i*2], samples[i*2+1]
+		}
+	}
+}
+
+// read copies the n frames of s that precede the given frame into buf,
+// oldest first.
+func (s scope) read(end int64, n int, buf []float32) {
+	if n == 0 {
+		return
+	}
+	buf = buf[:n*2]
+	k := copy(buf, s[s.index(end-int64(n)):])
+	copy(buf[k:], s)
+}
+
+// scopes holds the signals recorded for visualization. Snapshots are passed
+// from Render to Player.Scopes through a triple buffer, so that neither side
+// waits for the other.
+type scopes struct {
+	frames   int
+	start    int64 // first frame recorded since playback last jumped
+	channels [64]scope
+	voices   []scope // nil if voice scopes are disabled
+	scratch  []float32
+
+	snapshots   [3]Scopes
+	state       atomic.Uint32 // index of the published snapshot | scopeFresh
+	back, front int           // snapshots owned by Render and Scopes
+}
+
+// SetScopeLength makes the player record the most recent frames of output of
+// each channel, and of each voice if voices is true, for use by oscilloscopes
+// and level meters; see Scopes. A length of zero, the default, disables
+// recording. SetScopeLength allocates memory for the recordings, but Render
+// still does not. It must not be called concurrently with Render or Scopes.
+func (p *Player) SetScopeLength(frames int, voices bool) {
+	if frames <= 0 {
+		p.scopes = nil
+		return
+	}
+	s := &scopes{frames: frames, scratch: make([]float32, scopeChunk*2)}
+	for i := range s.channels {
+		s.channels[i] = make(scope, frames*2)
+	}
+	if voices {
+		s.voices = make([]scope, len(p.voices))
+		for i := range s.voices {
+			s.voices[i] = make(scope, frames*2)
+		}
+	}
+	for i := range s.snapshots {
+		snap := &s.snapshots[i]
+		for ch := range snap.Channels {
+			snap.Channels[ch] = make([]float32, 0, frames*2)
+		}
+		snap.Voices = make([]VoiceStatus, len(p.voices))
+		if voices {
+			snap.VoiceScopes = make([][]float32, len(p.voices))
+			for j := range snap.VoiceScopes {
+				snap.VoiceScopes[j] = make([]float32, 0, frames*2)
+			}
+		}
+	}
+	s.back, s.front = 0, 2
+	s.state.Store(1)
+	p.scopes = s
+	p.restartScopes()
+}
+
+// Scopes returns a snapshot of the signals recorded at the end of the most
+// recent call to Render or RenderStems, or nil if recording is disabled; see
+// SetScopeLength. Scopes does not block or allocate, and may be called from
+// one goroutine, such as a user interface thread, while another calls Render.
+// The snapshot is valid until the next call to Scopes.
+func (p *Player) Scopes() *Scopes {
+	s := p.scopes
+	if s == nil {
+		return nil
+	}
+	if s.state.Load()&scopeFresh != 0 {
+		s.front = int(s.state.Swap(uint32(s.front)) &^ scopeFresh)
+	}
+	return &s.snapshots[s.front]
+}
+
+// publishScopes copies the recorded signals into a snapshot and makes it
+// available to Scopes.
+func (p *Player) publishScopes() {
+	s := p.scopes
+	snap := &s.snapshots[s.back]
+	snap.Frame = p.frame
+	n := p.scopeFrames(p.frame - s.start)
+	for ch := range s.channels {
+		snap.Channels[ch] = snap.Channels[ch][:n*2]
+		s.channels[ch].read(p.frame, n, snap.Channels[ch])
+	}
+	for i := range p.voices {
+		v := &p.voices[i]
+		snap.Voices[i] = v.status()
+		if s.voices != nil {
+			m := 0
+			if v.active {
+				m = p.scopeFrames(v.scoped)
+			}
+			snap.VoiceScopes[i] = snap.VoiceScopes[i][:m*2]
+			s.voices[i].read(p.frame, m, snap.VoiceScopes[i])
+		}
+	}
+	s.back = int(s.state.Swap(uint32(s.back)|scopeFresh) &^ scopeFresh)
+}
+
+// scopeFrames returns the number of recorded frames available for a signal
+// that has been recorded for the given number of frames.
+func (p *Player) scopeFrames(recorded int64) int {
+	if recorded < int64(p.scopes.frames) {
+		return int(recorded)
+	}
+	return p.scopes.frames
+}
+
+// mixScoped is like mixVoice, but also records the output of v in the scope
+// of its host channel and in vs, if not nil.
+func (p *Player) mixScoped(v *voice, vs scope, buf []float32, pos, n int,
+	gain, delta float32) {
+	frame := p.frame + int64(pos)
+	for n > 0 {
+		chunk := n
+		if chunk > scopeChunk {
+			chunk = scopeChunk
+		}
+		scratch := p.scopes.scratch[:chunk*2]
+		for i := range scratch {
+			scratch[i] = 0
+		}
+		v.mix(scratch, chunk, gain, delta)
+		if buf != nil {
+			out := buf[pos*2 : (pos+chunk)*2]
+			for i, s := range scratch {
+				out[i] += s
+			}
+		}
+		p.scopes.channels[v.channel].add(frame, scratch)
+		if vs != nil {
+			vs.write(frame, chunk, scratch)
+			v.scoped += int64(chunk)
+		}
+		gain += delta * float32(chunk)
+		frame += int64(chunk)
+		pos += chunk
+		n -= chunk
+	}
+}
+
+// voiceScope returns the scope of the voice with the given index, or nil if
+// voice scopes are disabled.
+func (p *Player) voiceScope(i int) scope {
+	if p.scopes == nil || p.scopes.voices == nil {
+		return nil
+	}
+	return p.scopes.voices[i]
+}
+
+// restartScopes discards the recorded signals, because playback has jumped
+// to the current frame.
+func (p *Player) restartScopes() {
+	if p.scopes != nil {
+		p.scopes.start = p.frame
+	}
+}
+
+// clearScopes silences the channel scopes for n frames starting at the given
+// frame, before voices are mixed into them.
+func (p *Player) clearScopes(frame int64, n int) {
+	for _, s := range p.scopes.channels {
+		s.write(frame, n, nil)
+	}
+}
@@ -0,0 +1,135 @@
+package player
+
+import (
+	"math"
+	"sync"
+	"testing"
+)
+
+func TestScopes(t *testing.T) {
+	const length = 1024
+	m := stemModule()
+	p := New(m, testRate)
+	if p.Scopes() != nil {
+		t.Errorf("Scopes() returned snapshot while disabled")
+	}
+
+	p.SetScopeLength(length, true)
+	if s := p.Scopes(); s == nil || len(s.Channels[0]) != 0 {
+		t.Errorf("Scopes() before rendering is not empty")
+	}
+	out := make([]float32, 2*rowFrames)
+	p.Render(out)
+	s := p.Scopes()
+	if s.Frame != rowFrames {
+		t.Errorf("Scopes().Frame == %d; want %d", s.Frame, rowFrames)
+	}
+
+	// the channel scopes add up to the output
+	sum := make([]float32, 2*length)
+	for ch, buf := range s.Channels {
+		if len(buf) != 2*length {
+			t.Fatalf("len(Channels[%d]) == %d; want %d", ch, len(buf), 2*length)
+		}
+		if ch >= 2 && peak(buf) != 0 {
+			t.Errorf("Channels[%d] is not silent", ch)
+		}
+		for i := range sum {
+			sum[i] += buf[i]
+		}
+	}
+	if !equalOutput(sum, out[len(out)-len(sum):]) {
+		t.Errorf("sum of channel scopes does not match output")
+	}
+
+	// a square wave has equal peak and RMS levels
+	want := peak(s.Channels[0])
+	if l := s.ChannelLevel(0); l.Peak[0] != want ||
+		math.Abs(float64(l.RMS[0]-want)) > 0.01 {
+		t.Errorf("ChannelLevel(0) == %+v; want peak and RMS %v", l, want)
+	}
+
+	// voices
+	found := 0
+	for i, v := range s.Voices {
+		if !v.Active {
+			if len(s.VoiceScopes[i]) != 0 {
+				t.Errorf("VoiceScopes[%d] of inactive voice is not empty", i)
+			}
+			continue
+		}
+		found++
+		if v.Position <= 0 || v.Volume[0] == 0 || v.Sample != 1 ||
+			v.Instrument != v.Channel+1 {
+			t.Errorf("Voices[%d] == %+v", i, v)
+		}
+		if n := len(s.VoiceScopes[i]); n != 2*length ||
+			peak(s.VoiceScopes[i]) == 0 {
+			t.Errorf("VoiceScopes[%d] has %d samples, peak %v", i, n,
+				peak(s.VoiceScopes[i]))
+		}
+		if l := s.VoiceLevel(i); l.Peak[0] == 0 {
+			t.Errorf("VoiceLevel(%d) == %+v", i, l)
+		}
+	}
+	if found != 2 {
+		t.Errorf("found %d active voices; want 2", found)
+	}
+
+	// a snapshot is kept until a newer one is published
+	if p.Scopes() != s {
+		t.Errorf("Scopes() changed without rendering")
+	}
+
+	// seeking discards the recorded signals
+	p.Seek(0, 2)
+	p.Render(out[:2*100])
+	if s := p.Scopes(); len(s.Channels[0]) != 2*100 {
+		t.Errorf("len(Channels[0]) after seek == %d; want %d",
+			len(s.Channels[0]), 2*100)
+	}
+
+	// voice scopes are optional
+	p.SetScopeLength(length, false)
+	p.Seek(0, 0)
+	p.Render(out)
+	if s := p.Scopes(); s.VoiceScopes != nil || !s.Voices[0].Active {
+		t.Errorf("snapshot without voice scopes == %+v", s.Voices[0])
+	}
+}
+
+func TestScopesConcurrent(t *testing.T) {
+	p := New(busyModule(), testRate)
+	p.SetLoops(-1)
+	p.SetScopeLength(256, true)
+	buf := make([]float32, 2*512)
+
+	var wg sync.WaitGroup
+	done := make(chan bool)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		last := int64(0)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			s := p.Scopes()
+			if s.Frame < last {
+				t.Errorf("snapshot frame went from %d to %d", last, s.Frame)
+				return
+			}
+			last = s.Frame
+			for ch := range s.Channels {
+				s.ChannelLevel(ch)
+			}
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		p.Render(buf)
+	}
+	close(done)
+	wg.Wait()
+}
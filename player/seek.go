@@ -0,0 +1,109 @@
+package player
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var errSeekPastEnd = errors.New("seek past end of song")
+
+// skipTick discards the rest of the current tick, stops all voices, and
+// processes the next tick.
+func (p *Player) skipTick() {
+	p.frame += int64(p.tickFrames)
+	if p.fadeLeft > 0 {
+		if p.fadeLeft -= int64(p.tickFrames); p.fadeLeft <= 0 {
+			p.fadeLeft = 0
+			p.done = true
+		}
+	}
+	p.tickFrames = 0
+	p.stopVoices()
+	p.processTick()
+	p.pendingEvent = p.frame
+}
+
+// Seek moves playback to the first time that the given row of the given
+// order is played, starting from the beginning of the song. The speed, tempo,
+// global volume, channel volumes, and other effect state are reconstructed by
+// simulating playback up to the target row. Notes played before the target
+// row are not resumed. If the row is never played, Seek returns an error and
+// leaves playback unchanged.
+func (p *Player) Seek(order, row int) error {
+	if err := p.simulation().seekRow(order, row); err != nil {
+		return err
+	}
+	return p.seekRow(order, row)
+}
+
+// seekRow is like Seek, but leaves playback at the point where the search
+// stopped if the row is never played.
+func (p *Player) seekRow(order, row int) error {
+	p.reset()
+	for !p.done && p.it.Loops() == 0 {
+		p.skipTick()
+		pos := p.it.Position()
+		if p.newRow && !p.done && pos.Order == order && pos.Row == row {
+			return nil
+		}
+	}
+	return fmt.Errorf("order %d, row %d is not played", order, row)
+}
+
+// SeekTime moves playback to the given time offset from the start of the
+// song, reconstructing playback state in the same way as Seek. If playback
+// ends before the given time, SeekTime returns an error and leaves playback
+// unchanged.
+func (p *Player) SeekTime(t time.Duration) error {
+	target := int64(t.Seconds() * float64(p.rate))
+	if err := p.simulation().seekFrame(target); err != nil {
+		return err
+	}
+	return p.seekFrame(target)
+}
+
+// seekFrame moves playback to the given frame offset from the start of the
+// song, or to the end of the song if it ends before then.
+func (p *Player) seekFrame(target int64) error {
+	if target < p.frame {
+		p.reset()
+	}
+	for p.frame+int64(p.tickFrames) <= target {
+		if p.done {
+			return errSeekPastEnd
+		}
+		p.skipTick()
+	}
+	p.tickFrames -= int(target - p.frame)
+	p.frame = target
+	p.restartScopes()
+	return nil
+}
+
+// simulation returns a Player at the start of p's song with the same playback
+// settings, but without handlers or recorded signals, so that seeking can be
+// tried without affecting p.
+func (p *Player) simulation() *Player {
+	q := &Player{
+		mod:        p.mod,
+		rate:       p.rate,
+		samples:    p.samples,
+		interp:     p.interp,
+		quirks:     p.quirks,
+		loops:      p.loops,
+		rampFrames: p.rampFrames,
+		fadeFrames: p.fadeFrames,
+		macros:     p.macros,
+		macroBuf:   make([]byte, 0, 32),
+		event:      Event{Notes: make([]NoteEvent, 0, 64)},
+	}
+	q.controls.reset()
+	q.reset()
+	return q
+}
+
+// Time returns the time offset of playback from the start of the song.
+func (p *Player) Time() time.Duration {
+	return time.Duration(p.frame) * time.Second / time.Duration(p.rate)
+}
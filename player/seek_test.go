@@ -0,0 +1,119 @@
+package player
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jangler/impulse"
+)
+
+// stateModule returns a module that changes playback state on rows 0-3.
+func stateModule() *impulse.Module {
+	m := testModule()
+	m.OrderList = []uint8{0, 0, impulse.OrderEnd}
+	rows := m.Patterns[0].Rows
+	rows[0][0] = cell(60, 1, impulse.VolPanNone, effectV, 0x20)
+	rows[1][0] = cell(impulse.NoteNone, 0, impulse.VolPanNone, effectT, 150)
+	rows[2][3] = cell(impulse.NoteNone, 0, impulse.VolPanNone, effectM, 0x10)
+	rows[3][0] = cell(impulse.NoteNone, 0, impulse.VolPanNone, effectA, 3)
+	return m
+}
+
+func TestSeek(t *testing.T) {
+	p := New(stateModule(), testRate)
+	buf := make([]float32, 1024)
+	p.Render(buf)
+
+	if err := p.Seek(1, 8); err != nil {
+		t.Fatalf("Player.Seek(1, 8) returned error: %v", err)
+	}
+	pos := impulse.Position{Order: 1, Pattern: 0, Row: 8}
+	if got := p.Position(); got != pos {
+		t.Errorf("Player.Position() == %v; want %v", got, pos)
+	}
+	if p.globalVolume != 0x20 || p.tempo != 150 || p.speed != 3 ||
+		p.channels[3].chanVolume != 0x10 {
+		t.Errorf("state after seek: global volume %d, tempo %d, speed %d, "+
+			"channel volume %d", p.globalVolume, p.tempo, p.speed,
+			p.channels[3].chanVolume)
+	}
+	for i := range p.voices {
+		if p.voices[i].active {
+			t.Errorf("voice %d active after seek", i)
+		}
+	}
+
+	// frame count matches the time taken to reach the row
+	want := 2.5/125*6 + 2.5/150*(12+3*(29+8))
+	if got := p.Time().Seconds(); got < want-0.001 || got > want+0.001 {
+		t.Errorf("Player.Time() == %v; want %v", got, want)
+	}
+
+	// seek to a row that is never played
+	before := p.Time()
+	if err := p.Seek(2, 0); err == nil {
+		t.Errorf("Player.Seek(2, 0) did not return error")
+	}
+	if got := p.Time(); got != before {
+		t.Errorf("Player.Time() after failed seek == %v; want %v", got, before)
+	}
+	if got := p.Position(); got != pos {
+		t.Errorf("Player.Position() after failed seek == %v; want %v", got, pos)
+	}
+}
+
+func TestSeekTime(t *testing.T) {
+	p := New(stateModule(), testRate)
+	if err := p.SeekTime(time.Second); err != nil {
+		t.Fatalf("Player.SeekTime() returned error: %v", err)
+	}
+	if got, want := p.Time(), time.Second; got != want {
+		t.Errorf("Player.Time() == %v; want %v", got, want)
+	}
+	if p.tempo != 150 || p.speed != 3 {
+		t.Errorf("tempo, speed after seek == %d, %d; want 150, 3", p.tempo,
+			p.speed)
+	}
+
+	// seeking backward restarts playback
+	if err := p.SeekTime(50 * time.Millisecond); err != nil {
+		t.Fatalf("Player.SeekTime() returned error: %v", err)
+	}
+	if got, want := p.Position(), (impulse.Position{}); got != want {
+		t.Errorf("Player.Position() == %v; want %v", got, want)
+	}
+	if got, want := p.globalVolume, 0x20; got != want {
+		t.Errorf("global volume after seek == %d; want %d", got, want)
+	}
+
+	// a failed seek leaves playback unchanged
+	if err := p.SeekTime(time.Hour); err == nil {
+		t.Errorf("Player.SeekTime(time.Hour) did not return error")
+	}
+	if got, want := p.Time(), 50*time.Millisecond; got != want {
+		t.Errorf("Player.Time() after failed seek == %v; want %v", got, want)
+	}
+	if got, want := p.Position(), (impulse.Position{}); got != want {
+		t.Errorf("Player.Position() after failed seek == %v; want %v", got,
+			want)
+	}
+}
+
+func TestSetLoops(t *testing.T) {
+	m := testModule()
+	once := len(render(m))
+	p := New(m, testRate)
+	p.SetLoops(2)
+	buf := make([]float32, 1024)
+	total := 0
+	for {
+		n := p.Render(buf)
+		total += n * 2
+		if n < len(buf)/2 {
+			break
+		}
+	}
+	if d := total - once*3; d < -4 || d > 4 {
+		t.Errorf("rendered %d samples with 2 loops; want %d", total, once*3)
+	}
+}
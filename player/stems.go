@@ -0,0 +1,48 @@
+package player
+
+// StemMode determines how RenderStems groups voices into stems.
+type StemMode int
+
+const (
+	// StemsByChannel renders one stem per pattern channel. Background voices
+	// created by new note actions belong to the channel that started them.
+	StemsByChannel StemMode = iota
+
+	// StemsByInstrument renders one stem per instrument, or per sample if
+	// the module does not use instruments. Stem i contains instrument i+1.
+	StemsByInstrument
+)
+
+// RenderStems is like Render, but mixes each voice into one of several
+// interleaved stereo buffers as determined by mode. Voices belonging to a stem
+// that is out of range or nil are not rendered. All non-nil stems must have
+// the same length. Clipping set by SetClipping is not applied to stems, so
+// the sum of the stems is equal to the output of Render only with NoClipping.
+// Like Render, RenderStems does not allocate memory.
+func (p *Player) RenderStems(stems [][]float32, mode StemMode) int {
+	frames := -1
+	for _, buf := range stems {
+		if buf == nil {
+			continue
+		}
+		if frames < 0 || len(buf)/2 < frames {
+			frames = len(buf) / 2
+		}
+		for i := range buf {
+			buf[i] = 0
+		}
+	}
+	if frames < 0 {
+		return 0
+	}
+	return p.render(frames, func(v *voice) []float32 {
+		i := v.channel
+		if mode == StemsByInstrument {
+			i = int(v.insNum) - 1
+		}
+		if i < 0 || i >= len(stems) {
+			return nil
+		}
+		return stems[i]
+	})
+}
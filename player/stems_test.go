@@ -0,0 +1,98 @@
+package player
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jangler/impulse"
+)
+
+// stemModule returns a module with two instruments, where channel 0 leaves a
+// note playing in the background and channel 1 plays the second instrument.
+func stemModule() *impulse.Module {
+	m := testModule()
+	m.Flags |= impulse.UseInstruments
+	for i := 0; i < 2; i++ {
+		ins := &impulse.Instrument{
+			NewNoteAction:   impulse.NewNoteContinue,
+			GlobalVolume:    128,
+			VolumeEnvelope:  &impulse.Envelope{},
+			PanningEnvelope: &impulse.Envelope{},
+			PitchEnvelope:   &impulse.Envelope{},
+		}
+		for j := range ins.KeyboardTable {
+			ins.KeyboardTable[j] = impulse.NoteSample{Note: uint8(j), Sample: 1}
+		}
+		m.Instruments = append(m.Instruments, ins)
+	}
+	rows := m.Patterns[0].Rows
+	rows[0][0] = cell(60, 1, 32, 0, 0)
+	rows[4][0] = cell(72, 1, 32, 0, 0)
+	rows[0][1] = cell(48, 2, 32, 0, 0)
+	m.Patterns[0].Rows = rows[:8]
+	return m
+}
+
+// renderStems renders all of m into the given number of stems.
+func renderStems(m *impulse.Module, n int, mode StemMode) [][]float32 {
+	p := New(m, testRate)
+	out := make([][]float32, n)
+	bufs := make([][]float32, n)
+	for i := range bufs {
+		bufs[i] = make([]float32, 4096)
+	}
+	for {
+		frames := p.RenderStems(bufs, mode)
+		for i := range out {
+			out[i] = append(out[i], bufs[i][:frames*2]...)
+		}
+		if frames < 2048 {
+			return out
+		}
+	}
+}
+
+func TestRenderStems(t *testing.T) {
+	m := stemModule()
+	mix := render(m)
+
+	for _, mode := range []StemMode{StemsByChannel, StemsByInstrument} {
+		stems := renderStems(m, 2, mode)
+		for i, stem := range stems {
+			if len(stem) != len(mix) {
+				t.Fatalf("mode %d: len(stems[%d]) == %d; want %d",
+					mode, i, len(stem), len(mix))
+			}
+			if peak(stem) == 0 {
+				t.Errorf("mode %d: stem %d is silent", mode, i)
+			}
+		}
+		for i := range mix {
+			d := mix[i] - stems[0][i] - stems[1][i]
+			if math.Abs(float64(d)) > 1e-5 {
+				t.Fatalf("mode %d: sum of stems differs from mix at %d by %v",
+					mode, i, d)
+			}
+		}
+	}
+
+	// the background voice remains in the stem of the channel that started it
+	stems := renderStems(m, 2, StemsByChannel)
+	single := peak(rowSlice(stems[0], 0, 4))
+	if got := peak(rowSlice(stems[0], 4, 8)); got <= single {
+		t.Errorf("channel 0 peak with background voice == %v; want > %v",
+			got, single)
+	}
+	before := peak(rowSlice(stems[1], 0, 4))
+	after := peak(rowSlice(stems[1], 4, 8))
+	if after != before {
+		t.Errorf("channel 1 peak after channel 0 note == %v; want %v",
+			after, before)
+	}
+
+	// voices without a stem are not rendered
+	stems = renderStems(m, 1, StemsByInstrument)
+	if len(stems[0]) != len(mix) {
+		t.Errorf("len(stems[0]) == %d; want %d", len(stems[0]), len(mix))
+	}
+}
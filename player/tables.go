@@ -0,0 +1,41 @@
+package player
+
+import "math"
+
+// windowed sinc interpolation kernel, indexed by fractional position and tap
+const (
+	sincTaps   = 8
+	sincPhases = 256
+)
+
+var sincTable [sincPhases][sincTaps]float32
+
+func init() {
+	for phase := range sincTable {
+		frac := float64(phase) / sincPhases
+		var sum float64
+		var weights [sincTaps]float64
+		for i := range weights {
+			x := float64(i-sincTaps/2+1) - frac
+			w := 1.0
+			if x != 0 {
+				w = math.Sin(math.Pi*x) / (math.Pi * x)
+			}
+			// Blackman window
+			t := math.Pi * x / (sincTaps / 2)
+			w *= 0.42 + 0.5*math.Cos(t) + 0.08*math.Cos(2*t)
+			weights[i] = w
+			sum += w
+		}
+		for i, w := range weights {
+			sincTable[phase][i] = float32(w / sum)
+		}
+	}
+}
+
+// retrigger volume modifiers for Qxy, indexed by x
+var retrigAdd = [16]int{0, -1, -2, -4, -8, -16, 0, 0, 0, 1, 2, 4, 8, 16, 0, 0}
+var retrigMul = [16][2]int{6: {2, 3}, 7: {1, 2}, 14: {3, 2}, 15: {2, 1}}
+
+// portamento speeds for the volume column g command
+var volColPorta = [10]uint8{0, 1, 4, 8, 16, 32, 64, 96, 128, 255}
@@ -0,0 +1,201 @@
+package player
+
+import (
+	"math"
+
+	"github.com/jangler/impulse"
+)
+
+// maximum number of simultaneously playing voices
+const maxVoices = 256
+
+// panSurround is the pan value for surround sound.
+const panSurround = 100
+
+// voice is a sample playing on a virtual channel. A voice is controlled by its
+// host channel until a new note moves it to the background.
+type voice struct {
+	Voice
+	channel    int  // index of host channel
+	foreground bool // false once the host channel has moved on to a new note
+
+	ins    *impulse.Instrument
+	insNum uint8 // instrument number, or sample number in sample mode
+	smpNum uint8 // sample number
+	note   uint8 // note after keyboard table mapping
+
+	// parameters copied from the host channel while in the foreground
+	freq       float64 // Hz, not including pitch modifiers
+	pitchDelta float64 // in 1/64 semitones
+	volume     int     // range 0->64
+	chanVolume int     // range 0->64
+	pan        int     // range 0->64, panSurround
+	muted      bool
+
+	fading   bool
+	fadeVol  int     // range 0->1024
+	volSwing float64 // volume multiplier from instrument volume swing
+
+	volEnv, panEnv, pitchEnv envelope
+	autoVib                  impulse.AutoVibrato
+
+	cutoff, resonance int // range 0->127
+	filter            Filter
+	filtering         bool
+
+	// mixing parameters computed each tick
+	lvol, rvol float32
+
+	// volume applied while mixing, which ramps toward lvol and rvol
+	curL, curR   float32
+	rampL, rampR float32 // change per frame
+	rampLeft     int     // frames left in the ramp
+	stopping     bool    // true if the voice stops at the end of the ramp
+
+	scoped int64 // frames of the current note recorded in the voice's scope
+}
+
+// start begins playback of a sample on v.
+func (v *voice) start(ch int, s *impulse.Sample, data []float32,
+	ins *impulse.Instrument, insNum, note uint8) {
+	*v = voice{
+		Voice:      Voice{sample: s, data: data, active: true, keyOn: true},
+		channel:    ch,
+		foreground: true,
+		ins:        ins,
+		insNum:     insNum,
+		note:       note,
+		fadeVol:    1024,
+		volSwing:   1,
+		cutoff:     127,
+	}
+	v.autoVib.Reset(s)
+	v.restartEnvelopes()
+}
+
+// restartEnvelopes restarts the envelopes and fadeout of v, and presses its
+// key again.
+func (v *voice) restartEnvelopes() {
+	v.keyOn, v.fading, v.fadeVol = true, false, 1024
+	if v.ins != nil {
+		v.volEnv.reset(v.ins.VolumeEnvelope)
+		v.panEnv.reset(v.ins.PanningEnvelope)
+		v.pitchEnv.reset(v.ins.PitchEnvelope)
+	}
+}
+
+// noteOff releases the sustain of v.
+func (v *voice) noteOff() {
+	v.keyOn = false
+	if v.ins != nil {
+		env := v.ins.VolumeEnvelope
+		if !v.volEnv.on || env.Flags&impulse.EnvelopeLoopOn != 0 {
+			v.fading = true
+		}
+	}
+}
+
+// noteFade starts the fadeout of v.
+func (v *voice) noteFade() {
+	if v.ins != nil {
+		v.fading = true
+	}
+}
+
+// update processes envelopes and fadeout for one tick and computes the mixing
+// parameters of v.
+func (v *voice) update(p *Player) {
+	v.volEnv.advance(v.keyOn)
+	v.panEnv.advance(v.keyOn)
+	v.pitchEnv.advance(v.keyOn)
+
+	if v.volEnv.on && v.volEnv.ended {
+		if v.volEnv.value == 0 {
+			v.active = false
+			return
+		}
+		v.fading = true
+	}
+	if v.fading {
+		v.fadeVol -= int(v.ins.FadeOut)
+		if v.fadeVol <= 0 {
+			v.active = false
+			return
+		}
+	}
+
+	// volume
+	chanVolume := v.chanVolume
+	if o := p.controls.volume[v.channel].Load(); o >= 0 {
+		chanVolume = int(o)
+	}
+	vol := float64(v.volume) / 64 * float64(chanVolume) / 64 *
+		float64(v.sample.GlobalVolume) / 64 * float64(p.globalVolume) / 128 *
+		float64(v.fadeVol) / 1024 * v.volSwing
+	if v.ins != nil {
+		vol *= float64(v.ins.GlobalVolume) / 128
+	}
+	if v.volEnv.on {
+		vol *= v.volEnv.value / 64
+	}
+	if v.muted || !p.audible(v) {
+		vol = 0
+	}
+
+	// panning
+	pan := float64(v.pan)
+	if o := p.controls.pan[v.channel].Load(); o >= 0 {
+		pan = float64(o)
+	} else if v.pan == panSurround {
+		pan = 32
+	} else if v.panEnv.on {
+		pan += v.panEnv.value * (32 - math.Abs(pan-32)) / 32
+	}
+	pan = p.stereoPan(pan)
+	vol *= float64(p.mod.MixingVolume) / 128
+	v.lvol = float32(vol * (64 - pan) / 64)
+	v.rvol = float32(vol * pan / 64)
+	v.setRamp(p.rampFrames)
+
+	// pitch
+	delta := v.pitchDelta
+	filterEnv := v.pitchEnv.on && v.ins.PitchEnvelope.IsFilter()
+	if v.pitchEnv.on && !filterEnv {
+		delta += v.pitchEnv.value * 32
+	}
+	delta += float64(v.autoVib.Next())
+	v.step = v.freq * math.Exp2(delta/768) / float64(p.rate)
+
+	// filter
+	v.filtering = filterEnv || filterEnabled(v.cutoff, v.resonance)
+	if v.filtering {
+		env := 32.0
+		if filterEnv {
+			env = v.pitchEnv.value
+		}
+		v.filter.Set(v.cutoff, v.resonance, env, p.rate)
+	}
+}
+
+// mix adds n frames of v to buf, which contains interleaved stereo samples.
+// The output is scaled by gain, which changes by delta after each frame.
+func (v *voice) mix(buf []float32, n int, gain, delta float32) {
+	for i := 0; i < n && v.active; i++ {
+		s := v.value()
+		if v.filtering {
+			s = v.filter.Next(s)
+		}
+		s *= gain
+		buf[i*2] += s * v.curL
+		buf[i*2+1] += s * v.curR
+		v.advance(1)
+		v.stepRamp(1)
+		gain += delta
+	}
+}
+
+// skip advances v by n frames without mixing it.
+func (v *voice) skip(n int) {
+	v.advance(n)
+	v.stepRamp(n)
+}
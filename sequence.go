@@ -0,0 +1,249 @@
+package impulse
+
+// Effect commands that affect the order of playback.
+const (
+	effectJump  = 'B' - '@'
+	effectBreak = 'C' - '@'
+	effectS     = 'S' - '@'
+)
+
+// Special values in Module.OrderList.
+const (
+	OrderSkip uint8 = 254 // "+++" marker, skipped during playback
+	OrderEnd  uint8 = 255 // "---" marker, end of song
+)
+
+// emptyRow is the row data of patterns that do not exist.
+var emptyRow = func() (row [64]Cell) {
+	for i := range row {
+		row[i] = EmptyCell
+	}
+	return
+}()
+
+// Position is a location in the playback of a Module.
+type Position struct {
+	Order   int // index in Module.OrderList
+	Pattern int // index in Module.Patterns
+	Row     int
+}
+
+// RowIterator walks through the rows of a Module in the order that they are
+// played, following position jumps (Bxx), pattern breaks (Cxx), and pattern
+// loops (SBx).
+type RowIterator struct {
+	m        *Module
+	pos      Position
+	maxLoops int
+	loops    int
+	started  bool
+	done     bool
+	looped   bool
+	visited  [][]bool  // indexed by order, then row
+	loopRow  [64]int   // SBx loop start row for each channel
+	loopLeft [64]int   // SBx loop iterations left for each channel
+	sMemory  [64]uint8 // last Sxx parameter of each channel, for S00
+
+	// LoopResetsStart controls what happens to a channel's pattern loop start
+	// row when an SBx loop finishes. If true, the start row is set to the row
+	// following the SBx command, as in Impulse Tracker. If false, the start
+	// row is left unchanged.
+	LoopResetsStart bool
+}
+
+// Iterate returns a RowIterator for m. The iterator stops when playback would
+// return to a previously played row for the (loops+1)th time; if loops is
+// negative, the iterator never stops unless the module contains no patterns
+// to play.
+func (m *Module) Iterate(loops int) *RowIterator {
+	it := &RowIterator{
+		m:               m,
+		maxLoops:        loops,
+		visited:         make([][]bool, len(m.OrderList)),
+		LoopResetsStart: true,
+	}
+	for i, v := range m.OrderList {
+		if v < OrderSkip {
+			it.visited[i] = make([]bool, it.numRows(int(v)))
+		}
+	}
+	return it
+}
+
+// pattern returns the pattern at index i of m.Patterns, or nil if there is no
+// such pattern.
+func (m *Module) pattern(i int) *Pattern {
+	if i >= 0 && i < len(m.Patterns) && m.Patterns[i] != nil &&
+		len(m.Patterns[i].Rows) > 0 {
+		return m.Patterns[i]
+	}
+	return nil
+}
+
+func (it *RowIterator) numRows(pattern int) int {
+	if p := it.m.pattern(pattern); p != nil {
+		return len(p.Rows)
+	}
+	return 64
+}
+
+// Position returns the current position of the iterator. After Next returns
+// false because of a loop, Position returns the position that playback would
+// loop back to.
+func (it *RowIterator) Position() Position {
+	return it.pos
+}
+
+// Cells returns the pattern data for the current row. The returned data must
+// not be modified.
+func (it *RowIterator) Cells() *[64]Cell {
+	if p := it.m.pattern(it.pos.Pattern); p != nil {
+		return &p.Rows[it.pos.Row]
+	}
+	return &emptyRow
+}
+
+// Loops returns the number of times that playback has looped so far.
+func (it *RowIterator) Loops() int {
+	return it.loops
+}
+
+// Looped returns true if the current row was reached by playback looping back
+// to a previously played row.
+func (it *RowIterator) Looped() bool {
+	return it.looped
+}
+
+// seekOrder sets the iterator's order to the first playable order at or after
+// i, wrapping around to the start of the order list if the end of the song is
+// reached. It returns false if there are no playable orders.
+func (it *RowIterator) seekOrder(i int) bool {
+	wrapped := false
+	for {
+		if i >= len(it.m.OrderList) || it.m.OrderList[i] == OrderEnd {
+			if wrapped {
+				return false
+			}
+			i, wrapped = 0, true
+			continue
+		}
+		if it.m.OrderList[i] != OrderSkip {
+			break
+		}
+		i++
+	}
+	if i != it.pos.Order || !it.started {
+		// pattern loops do not carry across patterns
+		it.loopRow = [64]int{}
+		it.loopLeft = [64]int{}
+	}
+	it.pos.Order = i
+	it.pos.Pattern = int(it.m.OrderList[i])
+	return true
+}
+
+// Next advances the iterator to the next row to be played. It returns false
+// when iteration is done.
+func (it *RowIterator) Next() bool {
+	if it.done {
+		return false
+	}
+	it.looped = false
+
+	if !it.started {
+		if !it.seekOrder(0) {
+			it.done = true
+			return false
+		}
+		it.started = true
+		it.pos.Row = 0
+		it.visited[it.pos.Order][0] = true
+		return true
+	}
+
+	// process flow control effects in the current row
+	jumpOrder, breakRow, loopRow := -1, -1, -1
+	for ch, c := range it.Cells() {
+		switch c.Command {
+		case effectJump:
+			jumpOrder = int(c.Parameter)
+		case effectBreak:
+			breakRow = int(c.Parameter)
+		case effectS:
+			param := c.Parameter
+			if param == 0 {
+				param = it.sMemory[ch]
+			}
+			it.sMemory[ch] = param
+			if param>>4 != 0xb {
+				break
+			}
+			x := int(param & 0xf)
+			switch {
+			case x == 0:
+				it.loopRow[ch] = it.pos.Row
+			case it.loopLeft[ch] == 0:
+				it.loopLeft[ch] = x
+				loopRow = it.loopRow[ch]
+			default:
+				it.loopLeft[ch]--
+				if it.loopLeft[ch] > 0 {
+					loopRow = it.loopRow[ch]
+				} else if it.LoopResetsStart {
+					it.loopRow[ch] = it.pos.Row + 1
+				}
+			}
+		}
+	}
+
+	switch {
+	case loopRow >= 0:
+		// rows in the loop body are supposed to be played again
+		for i := loopRow; i <= it.pos.Row; i++ {
+			it.visited[it.pos.Order][i] = false
+		}
+		it.pos.Row = loopRow
+	case jumpOrder >= 0 || breakRow >= 0:
+		if jumpOrder < 0 {
+			jumpOrder = it.pos.Order + 1
+		}
+		if breakRow < 0 {
+			breakRow = 0
+		}
+		if !it.seekOrder(jumpOrder) {
+			it.done = true
+			return false
+		}
+		it.pos.Row = breakRow
+		if it.pos.Row >= it.numRows(it.pos.Pattern) {
+			it.pos.Row = 0
+		}
+	default:
+		it.pos.Row++
+		if it.pos.Row >= it.numRows(it.pos.Pattern) {
+			if !it.seekOrder(it.pos.Order + 1) {
+				it.done = true
+				return false
+			}
+			it.pos.Row = 0
+		}
+	}
+
+	// check for song loop
+	if it.visited[it.pos.Order][it.pos.Row] {
+		it.loops++
+		if it.maxLoops >= 0 && it.loops > it.maxLoops {
+			it.done = true
+			return false
+		}
+		it.looped = true
+		for _, rows := range it.visited {
+			for i := range rows {
+				rows[i] = false
+			}
+		}
+	}
+	it.visited[it.pos.Order][it.pos.Row] = true
+
+	return true
+}
@@ -0,0 +1,83 @@
+package impulse
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRowIterator(t *testing.T) {
+	// pattern 0: 4 rows, loops rows 1-2 once, then breaks to row 1 of next
+	// pattern on row 3
+	p0 := NewPattern(4)
+	p0.Rows[1][0] = Cell{Note: NoteNone, VolPan: VolPanNone, Command: effectS,
+		Parameter: 0xb0}
+	p0.Rows[2][0] = Cell{Note: NoteNone, VolPan: VolPanNone, Command: effectS,
+		Parameter: 0xb1}
+	p0.Rows[3][1] = Cell{Note: NoteNone, VolPan: VolPanNone,
+		Command: effectBreak, Parameter: 1}
+
+	// pattern 1: 2 rows, jumps back to order 2 on row 1, so the song loops
+	// when row 1 is reached for the second time
+	p1 := NewPattern(2)
+	p1.Rows[1][5] = Cell{Note: NoteNone, VolPan: VolPanNone,
+		Command: effectJump, Parameter: 2}
+
+	m := &Module{
+		OrderList: []uint8{0, OrderSkip, 1, OrderEnd},
+		Patterns:  []*Pattern{p0, p1},
+	}
+
+	var got []Position
+	var looped []bool
+	it := m.Iterate(1)
+	for it.Next() {
+		got = append(got, it.Position())
+		looped = append(looped, it.Looped())
+	}
+	want := []Position{
+		{0, 0, 0}, {0, 0, 1}, {0, 0, 2}, {0, 0, 1}, {0, 0, 2}, {0, 0, 3},
+		{2, 1, 1},
+		{2, 1, 0}, {2, 1, 1},
+		{2, 1, 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RowIterator positions == %v; want %v", got, want)
+	}
+	wantLooped := make([]bool, len(want))
+	wantLooped[8] = true
+	if !reflect.DeepEqual(looped, wantLooped) {
+		t.Errorf("RowIterator.Looped() == %v; want %v", looped, wantLooped)
+	}
+	if got, want := it.Loops(), 2; got != want {
+		t.Errorf("RowIterator.Loops() == %v; want %v", got, want)
+	}
+	if got, want := it.Position(), (Position{2, 1, 1}); got != want {
+		t.Errorf("RowIterator.Position() == %v; want %v", got, want)
+	}
+
+	// test module with nothing to play
+	m = &Module{OrderList: []uint8{OrderSkip, OrderEnd}}
+	if m.Iterate(0).Next() {
+		t.Errorf("RowIterator.Next() == true for empty module")
+	}
+}
+
+func TestRowIteratorSMemory(t *testing.T) {
+	// S00 on row 3 repeats the SB1 on row 2, looping row 3 once, since the
+	// first loop moved the loop start to row 3
+	p := NewPattern(5)
+	p.Rows[1][0] = Cell{Note: NoteNone, VolPan: VolPanNone, Command: effectS,
+		Parameter: 0xb0}
+	p.Rows[2][0] = Cell{Note: NoteNone, VolPan: VolPanNone, Command: effectS,
+		Parameter: 0xb1}
+	p.Rows[3][0] = Cell{Note: NoteNone, VolPan: VolPanNone, Command: effectS}
+	m := &Module{OrderList: []uint8{0, OrderEnd}, Patterns: []*Pattern{p}}
+
+	var got []int
+	for it := m.Iterate(0); it.Next(); {
+		got = append(got, it.Position().Row)
+	}
+	if want := []int{0, 1, 2, 1, 2, 3, 3, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("rows played == %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,77 @@
+package impulse
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// RowTime is the time at which a row starts playing.
+type RowTime struct {
+	Position
+	Time  time.Duration
+	Frame int64 // offset in sample frames at the rate given to Timing
+}
+
+// TimingMap lists the rows of a Module in the order that they are played,
+// along with the time at which each starts playing. Rows repeated by pattern
+// loops appear once per repetition.
+type TimingMap []RowTime
+
+// Timing simulates playback of m until the song loops, and returns the start
+// time and sample offset of each row played at the given sample rate in Hz.
+// Sample offsets match the output of the player package at the same rate.
+func (m *Module) Timing(sampleRate int) TimingMap {
+	var tm TimingMap
+	t := newTimer(m)
+	t.rate = sampleRate
+	var now time.Duration
+	it := m.Iterate(0)
+	for it.Next() {
+		tm = append(tm, RowTime{Position: it.Position(), Time: now,
+			Frame: t.frames})
+		now += t.row(it.Cells())
+	}
+	return tm
+}
+
+// jsonRowTime is the JSON representation of a RowTime.
+type jsonRowTime struct {
+	Order   int     `json:"order"`
+	Pattern int     `json:"pattern"`
+	Row     int     `json:"row"`
+	Time    float64 `json:"time"` // seconds
+	Frame   int64   `json:"frame"`
+}
+
+// WriteJSON writes tm to w as a JSON array of objects with "order",
+// "pattern", "row", "time", and "frame" fields. Times are in seconds.
+func (tm TimingMap) WriteJSON(w io.Writer) error {
+	rows := make([]jsonRowTime, len(tm))
+	for i, rt := range tm {
+		rows[i] = jsonRowTime{
+			Order:   rt.Order,
+			Pattern: rt.Pattern,
+			Row:     rt.Row,
+			Time:    rt.Time.Seconds(),
+			Frame:   rt.Frame,
+		}
+	}
+	return json.NewEncoder(w).Encode(rows)
+}
+
+// WriteLRC writes tm to w in the LRC lyrics format, with one line per row.
+// Each line is tagged with the row's start time and contains its order and
+// row numbers in the form "order:row", to be replaced with lyrics.
+func (tm TimingMap) WriteLRC(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, rt := range tm {
+		// LRC timestamps are [mm:ss.xx], in hundredths of a second
+		cs := rt.Time.Round(10*time.Millisecond) / (10 * time.Millisecond)
+		fmt.Fprintf(bw, "[%02d:%02d.%02d]%03d:%02d\n", cs/6000, cs/100%60,
+			cs%100, rt.Order, rt.Row)
+	}
+	return bw.Flush()
+}
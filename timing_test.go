@@ -0,0 +1,80 @@
+package impulse
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTiming(t *testing.T) {
+	p := NewPattern(4)
+	p.Rows[0][0] = Cell{Note: NoteNone, VolPan: VolPanNone,
+		Command: effectSpeed, Parameter: 3}
+	p.Rows[1][0] = Cell{Note: NoteNone, VolPan: VolPanNone,
+		Command: effectS, Parameter: 0xe1} // row plays twice
+	p.Rows[2][0] = Cell{Note: NoteNone, VolPan: VolPanNone,
+		Command: effectTempo, Parameter: 128} // 861.328125 frames per tick
+	m := &Module{
+		InitialSpeed: 6,
+		InitialTempo: 125,
+		OrderList:    []uint8{0, OrderEnd},
+		Patterns:     []*Pattern{p},
+	}
+
+	tm := m.Timing(44100)
+	want := []RowTime{
+		{Position{0, 0, 0}, 0, 0},
+		{Position{0, 0, 1}, 60 * time.Millisecond, 2646},
+		{Position{0, 0, 2}, 180 * time.Millisecond, 7938},
+		{Position{0, 0, 3}, 238593750 * time.Nanosecond, 10521},
+	}
+	if len(tm) != len(want) {
+		t.Fatalf("len(Timing()) == %d; want %d", len(tm), len(want))
+	}
+	for i, rt := range tm {
+		if rt.Position != want[i].Position || rt.Frame != want[i].Frame ||
+			!approxEqual(rt.Time, want[i].Time) {
+			t.Errorf("Timing()[%d] == %v; want %v", i, rt, want[i])
+		}
+	}
+
+	// JSON
+	var b bytes.Buffer
+	if err := tm.WriteJSON(&b); err != nil {
+		t.Fatalf("WriteJSON() returned error: %v", err)
+	}
+	var rows []map[string]float64
+	if err := json.Unmarshal(b.Bytes(), &rows); err != nil {
+		t.Fatalf("could not decode WriteJSON() output: %v", err)
+	}
+	if got, want := rows[2], map[string]float64{"order": 0, "pattern": 0,
+		"row": 2, "time": 0.18, "frame": 7938}; len(rows) != 4 ||
+		!equalRowJSON(got, want) {
+		t.Errorf("WriteJSON() row 2 == %v; want %v", got, want)
+	}
+
+	// LRC
+	b.Reset()
+	if err := tm.WriteLRC(&b); err != nil {
+		t.Fatalf("WriteLRC() returned error: %v", err)
+	}
+	lines := strings.Split(b.String(), "\n")
+	if got, want := lines[3], "[00:00.24]000:03"; got != want {
+		t.Errorf("WriteLRC() line 3 == %q; want %q", got, want)
+	}
+}
+
+// equalRowJSON returns true if two decoded JSON rows are equal.
+func equalRowJSON(a, b map[string]float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if d := v - b[k]; d > 1e-9 || d < -1e-9 {
+			return false
+		}
+	}
+	return true
+}
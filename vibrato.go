@@ -0,0 +1,82 @@
+package impulse
+
+import "math"
+
+// vibratoTables holds the sine, ramp down, and square waveforms.
+var vibratoTables [3][256]int8
+
+func init() {
+	for i := 0; i < 256; i++ {
+		sin := math.Sin(2 * math.Pi * float64(i) / 256)
+		vibratoTables[0][i] = int8(math.Round(64 * sin))
+		vibratoTables[1][i] = int8(64 - (i+1)/2)
+		if i < 128 {
+			vibratoTables[2][i] = 64
+		}
+	}
+}
+
+// Value returns the value of w at a position in the range 0->255, which wraps
+// around. Values range from -64 to 64. Random has no fixed values, and always
+// returns 0.
+func (w VibratoWaveform) Value(pos int) int {
+	if w >= Random {
+		return 0
+	}
+	return int(vibratoTables[w][pos&0xff])
+}
+
+// AutoVibrato generates the automatic vibrato of a Sample, one tick at a time,
+// as for a single playing note. Depth sweeps in at the sample's vibrato rate;
+// a rate of zero applies the full depth immediately.
+//
+// The zero value produces no vibrato; use Reset or Sample.AutoVibrato to start
+// one.
+type AutoVibrato struct {
+	sample *Sample
+	pos    int
+	sweep  int    // current depth, in 1/256 units
+	seed   uint32 // state of the random waveform
+}
+
+// AutoVibrato returns an AutoVibrato for a new note of s.
+func (s *Sample) AutoVibrato() *AutoVibrato {
+	a := &AutoVibrato{}
+	a.Reset(s)
+	return a
+}
+
+// Reset restarts a for a new note of s.
+func (a *AutoVibrato) Reset(s *Sample) {
+	*a = AutoVibrato{sample: s, seed: 1}
+}
+
+// Next returns the pitch offset for the next tick, in 1/64 semitones.
+func (a *AutoVibrato) Next() int {
+	s := a.sample
+	if s == nil || s.VibratoDepth == 0 {
+		return 0
+	}
+	max := int(s.VibratoDepth) << 8
+	if s.VibratoRate == 0 {
+		a.sweep = max
+	} else if a.sweep += int(s.VibratoRate); a.sweep > max {
+		a.sweep = max
+	}
+
+	var value int
+	if s.VibratoWaveform >= Random {
+		a.seed = a.seed*1103515245 + 12345
+		value = int(a.seed>>16)%129 - 64
+	} else {
+		value = s.VibratoWaveform.Value(a.pos)
+	}
+	a.pos += int(s.VibratoSpeed)
+	return value * (a.sweep >> 8) / 64
+}
+
+// Depth returns the current depth of a, which increases from 0 to the
+// sample's vibrato depth as the vibrato sweeps in.
+func (a *AutoVibrato) Depth() int {
+	return a.sweep >> 8
+}
@@ -0,0 +1,77 @@
+package impulse
+
+import "testing"
+
+func TestVibratoWaveformValue(t *testing.T) {
+	for _, c := range []struct {
+		w    VibratoWaveform
+		pos  int
+		want int
+	}{
+		{SineWave, 0, 0}, {SineWave, 64, 64}, {SineWave, 192, -64},
+		{SineWave, 256 + 64, 64},
+		{RampDown, 0, 64}, {RampDown, 255, -64},
+		{SquareWave, 0, 64}, {SquareWave, 127, 64}, {SquareWave, 128, 0},
+		{Random, 0, 0},
+	} {
+		if got := c.w.Value(c.pos); got != c.want {
+			t.Errorf("VibratoWaveform(%d).Value(%d) == %d; want %d",
+				c.w, c.pos, got, c.want)
+		}
+	}
+}
+
+func TestAutoVibrato(t *testing.T) {
+	s := &Sample{
+		VibratoSpeed:    64,
+		VibratoDepth:    32,
+		VibratoRate:     128,
+		VibratoWaveform: SineWave,
+	}
+
+	// depth sweeps in by half a unit per tick
+	a := s.AutoVibrato()
+	for i := 1; i <= 80; i++ {
+		a.Next()
+		want := i / 2
+		if want > 32 {
+			want = 32
+		}
+		if got := a.Depth(); got != want {
+			t.Fatalf("depth after %d ticks == %d; want %d", i, got, want)
+		}
+	}
+
+	// full depth applies immediately with rate 0; speed 64 is a quarter cycle
+	s.VibratoRate = 0
+	a.Reset(s)
+	want := []int{0, 32, 0, -32, 0}
+	for i, w := range want {
+		if got := a.Next(); got != w {
+			t.Errorf("tick %d: offset == %d; want %d", i, got, w)
+		}
+	}
+
+	// random values stay in range and repeat after a reset
+	s.VibratoWaveform = Random
+	a.Reset(s)
+	var first []int
+	for i := 0; i < 100; i++ {
+		v := a.Next()
+		if v < -32 || v > 32 {
+			t.Fatalf("random offset %d out of range", v)
+		}
+		first = append(first, v)
+	}
+	a.Reset(s)
+	for i, v := range first {
+		if got := a.Next(); got != v {
+			t.Fatalf("random offset %d after reset == %d; want %d", i, got, v)
+		}
+	}
+
+	var zero AutoVibrato
+	if got := zero.Next(); got != 0 {
+		t.Errorf("zero AutoVibrato.Next() == %d; want 0", got)
+	}
+}